@@ -0,0 +1,395 @@
+package git_auto_commit
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/template"
+	"github.com/ivy/git-auto-commit/util/diff"
+	"github.com/ivy/git-auto-commit/util/log"
+	"github.com/ivy/git-auto-commit/util/tokens"
+)
+
+// Diff strategies for config.DiffStrategy.
+const (
+	// strategyTruncate reduces generated and then the largest files to stat
+	// lines until the diff fits the budget.
+	strategyTruncate = "truncate"
+	// strategySummarize has the model summarize each file, then writes the
+	// message from the summaries.
+	strategySummarize = "summarize"
+	// strategyFull sends the diff as is, regardless of size.
+	strategyFull = "full"
+)
+
+// generatedNames are file names that are always generated, such as lock
+// files.
+var generatedNames = []string{
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"go.sum",
+	"package-lock.json",
+	"pnpm-lock.yaml",
+	"poetry.lock",
+	"yarn.lock",
+}
+
+// generatedSuffixes are file name suffixes of generated or minified files.
+var generatedSuffixes = []string{
+	".min.css",
+	".min.js",
+	".pb.go",
+	".snap",
+	"_generated.go",
+	"_gen.go",
+}
+
+// generatedDirs are directories whose contents are vendored or built.
+var generatedDirs = []string{
+	"dist",
+	"node_modules",
+	"vendor",
+}
+
+// isGenerated reports whether the file is likely generated, vendored, or
+// binary, so that its contents say little about the intent of a change.
+func isGenerated(f *diff.File) bool {
+	if f.IsBinary() {
+		return true
+	}
+
+	p := f.Path()
+	if slices.Contains(generatedNames, path.Base(p)) {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(p, suffix) {
+			return true
+		}
+	}
+	for _, dir := range strings.Split(path.Dir(p), "/") {
+		if slices.Contains(generatedDirs, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// statLine describes a file's change in one line, for files whose full diff
+// is left out of the prompt.
+func statLine(f *diff.File, reason string) string {
+	added, deleted := f.Stat()
+	return fmt.Sprintf("%s | +%d -%d (%s)", f.Path(), added, deleted, reason)
+}
+
+// tokenBudget returns the number of tokens the diff may use in a prompt.
+func tokenBudget(config *Config) int {
+	if config.TokenBudget > 0 {
+		return config.TokenBudget
+	}
+	return tokens.Budget(config.Model)
+}
+
+// fitDiff shrinks a staged diff to the configured token budget using the
+// configured strategy, so that it can be sent to the model. Diffs within the
+// budget are returned as is.
+func fitDiff(ctx context.Context, config *Config, staged string) (string, error) {
+	budget := tokenBudget(config)
+	estimate := tokens.Estimate(staged)
+
+	strategy, err := diffStrategy(config)
+	if err != nil {
+		return "", err
+	}
+
+	if strategy == strategyFull || estimate <= budget {
+		return staged, nil
+	}
+
+	log.Infow("staged diff is over the token budget",
+		"estimate", estimate,
+		"budget", budget,
+		"strategy", strategy)
+
+	files, err := diff.Parse(staged)
+	if err != nil {
+		// Without file boundaries, all we can do is cut the text short.
+		log.Warnw("failed to parse staged diff, truncating it",
+			"error", err)
+		return tokens.Truncate(staged, budget), nil
+	}
+
+	if strategy == strategySummarize {
+		return summarizeDiff(ctx, config, files, budget)
+	}
+	return truncateDiff(files, budget), nil
+}
+
+// diffStrategy returns the configured diff strategy, or an error if it's
+// unknown.
+func diffStrategy(config *Config) (string, error) {
+	switch config.DiffStrategy {
+	case "":
+		return strategyTruncate, nil
+	case strategyFull, strategyTruncate, strategySummarize:
+		return config.DiffStrategy, nil
+	}
+	return "", fmt.Errorf("unknown diff strategy %q", config.DiffStrategy)
+}
+
+// omitFiles picks the files to leave out of an over-budget diff: generated
+// files, then the largest remaining files until the rest fit in budget. It
+// returns the reason each one is left out.
+func omitFiles(files []*diff.File, budget int) map[*diff.File]string {
+	type entry struct {
+		file   *diff.File
+		tokens int
+	}
+
+	omitted := make(map[*diff.File]string)
+	entries := make([]entry, len(files))
+	total := 0
+	for i, f := range files {
+		entries[i] = entry{file: f, tokens: tokens.Estimate(f.String())}
+		if isGenerated(f) {
+			omitted[f] = "generated"
+		} else {
+			total += entries[i].tokens
+		}
+	}
+
+	// Drop the largest files first, since they're the most likely to be bulk
+	// changes and free up the most room.
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return b.tokens - a.tokens
+	})
+	for _, e := range entries {
+		if total <= budget {
+			break
+		}
+		if _, ok := omitted[e.file]; !ok {
+			omitted[e.file] = "too large"
+			total -= e.tokens
+		}
+	}
+	return omitted
+}
+
+// truncateDiff keeps the full hunks of as many source files as fit in budget,
+// and reduces generated files, then the largest remaining files, to stat
+// lines.
+func truncateDiff(files []*diff.File, budget int) string {
+	reasons := omitFiles(files, budget)
+
+	var (
+		kept    strings.Builder
+		omitted []string
+	)
+	for _, f := range files {
+		if reason, ok := reasons[f]; ok {
+			omitted = append(omitted, statLine(f, reason))
+		} else {
+			kept.WriteString(f.String())
+		}
+	}
+
+	if len(omitted) > 0 {
+		fmt.Fprintf(&kept,
+			"\nThe diffs of these files were left out to fit the model's context; only their line counts are shown:\n\n%s\n",
+			strings.Join(omitted, "\n"))
+	}
+	return kept.String()
+}
+
+// maxSummaryRequests caps the requests made to summarize one diff, so that a
+// change to hundreds of files doesn't make hundreds of calls. Files that don't
+// fit in that many requests are reduced to stat lines.
+const maxSummaryRequests = 16
+
+// summaryPiece is part of a diff to summarize: a whole file, or one part of a
+// file too large to summarize at once.
+type summaryPiece struct {
+	file        *diff.File
+	text        string
+	part, parts int
+}
+
+// label names the piece in the summaries.
+func (p summaryPiece) label() string {
+	if p.parts > 1 {
+		return fmt.Sprintf("%s (part %d of %d)", p.file.Path(), p.part, p.parts)
+	}
+	added, deleted := p.file.Stat()
+	return fmt.Sprintf("%s (+%d -%d)", p.file.Path(), added, deleted)
+}
+
+// splitFile divides a file's diff into pieces of at most budget tokens, at
+// hunk boundaries where possible, and at line boundaries within hunks that are
+// too large on their own.
+func splitFile(f *diff.File, budget int) []summaryPiece {
+	texts := []string{f.String()}
+	if tokens.Estimate(texts[0]) > budget {
+		texts = nil
+
+		var hunks []*diff.Hunk
+		flush := func() {
+			if len(hunks) > 0 {
+				texts = append(texts, f.Patch(hunks...))
+				hunks = nil
+			}
+		}
+		for _, h := range f.Hunks {
+			if tokens.Estimate(f.Patch(append(hunks, h)...)) <= budget {
+				hunks = append(hunks, h)
+				continue
+			}
+			flush()
+			if text := f.Patch(h); tokens.Estimate(text) > budget {
+				texts = append(texts, splitLines(text, budget)...)
+			} else {
+				hunks = []*diff.Hunk{h}
+			}
+		}
+		flush()
+	}
+
+	pieces := make([]summaryPiece, len(texts))
+	for i, text := range texts {
+		pieces[i] = summaryPiece{file: f, text: text, part: i + 1, parts: len(texts)}
+	}
+	return pieces
+}
+
+// splitLines cuts text into pieces of at most n tokens, at line boundaries
+// where possible.
+func splitLines(text string, n int) []string {
+	var pieces []string
+	for text != "" {
+		piece := tokens.Truncate(text, n)
+		if piece == "" {
+			piece = text
+		}
+		pieces = append(pieces, piece)
+		text = text[len(piece):]
+	}
+	return pieces
+}
+
+// summaryBatches packs pieces, in order, into batches of at most budget
+// tokens, each summarized in one request.
+func summaryBatches(pieces []summaryPiece, budget int) [][]summaryPiece {
+	var (
+		batches [][]summaryPiece
+		size    int
+	)
+	for _, p := range pieces {
+		n := tokens.Estimate(p.text)
+		if len(batches) == 0 || size+n > budget {
+			batches = append(batches, nil)
+			size = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], p)
+		size += n
+	}
+	return batches
+}
+
+// summarizeDiff asks the model to summarize the changes in batches of files
+// that fit the budget, splitting files that are too large into parts, then
+// joins the summaries to stand in for the diff. Generated files, and any that
+// don't fit in maxSummaryRequests requests, are reduced to stat lines. If the
+// summaries are still over budget, the diff is truncated instead.
+func summarizeDiff(ctx context.Context, config *Config, files []*diff.File, budget int) (string, error) {
+	var (
+		b       strings.Builder
+		omitted []string
+		pieces  []summaryPiece
+	)
+	for _, f := range files {
+		if isGenerated(f) {
+			omitted = append(omitted, statLine(f, "generated"))
+		} else {
+			pieces = append(pieces, splitFile(f, budget)...)
+		}
+	}
+
+	batches := summaryBatches(pieces, budget)
+	if len(batches) > maxSummaryRequests {
+		log.Warnw("too many files to summarize, leaving some out",
+			"requests", len(batches),
+			"max", maxSummaryRequests)
+
+		summarized := make(map[*diff.File]bool)
+		for _, batch := range batches[:maxSummaryRequests] {
+			for _, p := range batch {
+				summarized[p.file] = true
+			}
+		}
+		for _, batch := range batches[maxSummaryRequests:] {
+			for _, p := range batch {
+				if p.part != p.parts {
+					continue
+				}
+				reason := "not summarized"
+				if summarized[p.file] {
+					reason = "partly summarized"
+				}
+				omitted = append(omitted, statLine(p.file, reason))
+			}
+		}
+		batches = batches[:maxSummaryRequests]
+	}
+
+	fmt.Fprintln(&b, "The staged diff was too large to include, so its changes were summarized:")
+	for i, batch := range batches {
+		var (
+			paths  []string
+			labels []string
+			text   strings.Builder
+		)
+		for _, p := range batch {
+			paths = append(paths, p.file.Path())
+			labels = append(labels, p.label())
+			text.WriteString(p.text)
+		}
+
+		prompt, err := template.RenderString("prompt/summarize.tmpl", map[string]any{
+			"Path":    strings.Join(slices.Compact(paths), ", "),
+			"Diff":    text.String(),
+			"Partial": batch[0].parts > 1,
+			"Message": config.Message,
+		})
+		if err != nil {
+			log.Errorw("failed to execute summarize template",
+				"error", err)
+			return "", err
+		}
+
+		label := fmt.Sprintf("Summarizing %s (%d/%d)", strings.Join(slices.Compact(paths), ", "), i+1, len(batches))
+		if len(batch) > 1 {
+			label = fmt.Sprintf("Summarizing %d files (%d/%d)", len(batch), i+1, len(batches))
+		}
+		summary, err := generateQuietly(ctx, config, label, prompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize %s: %w", strings.Join(labels, ", "), err)
+		}
+
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", strings.Join(labels, ", "), strings.TrimSpace(summary))
+	}
+
+	if len(omitted) > 0 {
+		fmt.Fprintf(&b, "\nThese files also changed:\n\n%s\n", strings.Join(omitted, "\n"))
+	}
+
+	if estimate := tokens.Estimate(b.String()); estimate > budget {
+		log.Warnw("summaries are over the token budget, truncating the diff instead",
+			"estimate", estimate,
+			"budget", budget)
+		return truncateDiff(files, budget), nil
+	}
+	return b.String(), nil
+}
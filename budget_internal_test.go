@@ -0,0 +1,131 @@
+package git_auto_commit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/util/diff"
+	"github.com/ivy/git-auto-commit/util/tokens"
+)
+
+// fileDiff returns a diff that adds n lines to path.
+func fileDiff(path string, n int) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/" + path + " b/" + path + "\n")
+	b.WriteString("--- a/" + path + "\n")
+	b.WriteString("+++ b/" + path + "\n")
+	b.WriteString("@@ -1,0 +1,3 @@\n")
+	for range n {
+		b.WriteString("+some added line of code\n")
+	}
+	return b.String()
+}
+
+var _ = Describe("isGenerated", func() {
+	DescribeTable("recognizes generated files by path",
+		func(path string, expected bool) {
+			files, err := diff.Parse(fileDiff(path, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isGenerated(files[0])).To(Equal(expected))
+		},
+		Entry("lock file", "web/package-lock.json", true),
+		Entry("go.sum", "go.sum", true),
+		Entry("vendored code", "vendor/github.com/x/y.go", true),
+		Entry("minified script", "static/app.min.js", true),
+		Entry("source file", "main.go", false),
+		Entry("file named like a directory", "vendor.go", false),
+	)
+})
+
+var _ = Describe("fitDiff", func() {
+	var cfg *Config
+
+	BeforeEach(func() {
+		cfg = &Config{Config: &config.Config{Model: "fake-model", TokenBudget: 100}}
+	})
+
+	It("returns diffs within the budget unchanged", func() {
+		staged := fileDiff("main.go", 3)
+		Expect(fitDiff(context.Background(), cfg, staged)).To(Equal(staged))
+	})
+
+	It("sends the full diff with the full strategy", func() {
+		cfg.DiffStrategy = strategyFull
+		staged := fileDiff("main.go", 100)
+		Expect(fitDiff(context.Background(), cfg, staged)).To(Equal(staged))
+	})
+
+	It("rejects unknown strategies", func() {
+		cfg.DiffStrategy = "shrink"
+		_, err := fitDiff(context.Background(), cfg, "")
+		Expect(err).To(MatchError(`unknown diff strategy "shrink"`))
+	})
+
+	It("reduces generated files, then the largest, to stat lines", func() {
+		staged := fileDiff("main.go", 5) + fileDiff("go.sum", 5) + fileDiff("big.go", 40)
+
+		fitted, err := fitDiff(context.Background(), cfg, staged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fitted).To(HavePrefix(fileDiff("main.go", 5)))
+		Expect(fitted).To(ContainSubstring("go.sum | +5 -0 (generated)"))
+		Expect(fitted).To(ContainSubstring("big.go | +40 -0 (too large)"))
+		Expect(fitted).NotTo(ContainSubstring("+++ b/big.go"))
+	})
+})
+
+var _ = Describe("summary pieces", func() {
+	It("splits a file that's over budget at hunk and then line boundaries", func() {
+		text := fileDiff("big.go", 30) + "@@ -9,0 +40,3 @@\n+one small hunk\n"
+		files, err := diff.Parse(text)
+		Expect(err).NotTo(HaveOccurred())
+
+		pieces := splitFile(files[0], 100)
+		Expect(len(pieces)).To(BeNumerically(">", 2))
+
+		var joined strings.Builder
+		for i, p := range pieces {
+			Expect(tokens.Estimate(p.text)).To(BeNumerically("<=", 100))
+			Expect(p.label()).To(Equal(fmt.Sprintf("big.go (part %d of %d)", i+1, len(pieces))))
+			joined.WriteString(p.text)
+		}
+		Expect(joined.String()).To(ContainSubstring("+one small hunk"))
+		Expect(strings.Count(joined.String(), "+some added line of code")).To(Equal(30))
+	})
+
+	It("keeps a file within budget whole", func() {
+		files, err := diff.Parse(fileDiff("main.go", 3))
+		Expect(err).NotTo(HaveOccurred())
+
+		pieces := splitFile(files[0], 100)
+		Expect(pieces).To(HaveLen(1))
+		Expect(pieces[0].label()).To(Equal("main.go (+3 -0)"))
+	})
+
+	It("packs small files into one batch per budget", func() {
+		var staged string
+		for i := range 10 {
+			staged += fileDiff(fmt.Sprintf("f%d.go", i), 2)
+		}
+		files, err := diff.Parse(staged)
+		Expect(err).NotTo(HaveOccurred())
+
+		var pieces []summaryPiece
+		for _, f := range files {
+			pieces = append(pieces, splitFile(f, 100)...)
+		}
+		batches := summaryBatches(pieces, 100)
+		Expect(len(batches)).To(BeNumerically("<", len(files)))
+		for _, batch := range batches {
+			size := 0
+			for _, p := range batch {
+				size += tokens.Estimate(p.text)
+			}
+			Expect(size).To(BeNumerically("<=", 100))
+		}
+	})
+})
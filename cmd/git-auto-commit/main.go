@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/spf13/pflag"
 
 	git_auto_commit "github.com/ivy/git-auto-commit"
 	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/template"
 	"github.com/ivy/git-auto-commit/util/log"
 )
 
@@ -22,16 +25,27 @@ const (
 // Defaults to "dev" if not set.
 var Version = "dev"
 
+// subcommands are the commands that may be given instead of commit args.
+var subcommands = []string{"reword", "template", "hook"}
+
 // CLIFlags holds local CLI-only flags that are *not* in config.Config.
 type CLIFlags struct {
-	Verbose bool
-	Yes     bool
-	Message string
+	Verbose    bool
+	Yes        bool
+	Message    string
+	Candidates int
+	Amend      bool
+	Split      bool
+	DryRun     bool
+	Output     string
+	JSON       bool
+	FromDiff   string
+	FromRange  string
 }
 
 func main() {
 	// 1. Initialize the config package so it can register pflags
-	//    for --provider, --model, and --openai-key.
+	//    for --provider, --model, and the API key flags.
 	config.Init()
 
 	// 2. Local flags for this CLI.
@@ -53,14 +67,43 @@ License:     ISC License
 
 Usage:
   %s [options] [-- <extra git commit args>]
+  %s reword [options] <range>
+  %s template list|eject <name>...
+  %s hook install|uninstall|run <file> [<source> [<commit>]]
 
 Examples:
-  # Use GPT-o1, then pass --amend to git commit:
-  %s --model=gpt-o1 -- --amend
+  # Use GPT-o1, then pass --no-verify to git commit:
+  %s --model=gpt-o1 -- --no-verify
+
+  # Regenerate HEAD's message from all of its changes and amend it:
+  %s --amend
+
+  # Pick from three alternative messages:
+  %s --candidates 3
+
+  # Split the staged changes into several focused commits:
+  %s --split
+
+  # Print a message for the staged changes, with token usage, without committing:
+  %s --json
+
+  # Suggest a message for a patch that isn't staged:
+  git diff main...feature | %s --from-diff -
+
+  # Regenerate the message of every commit on this branch:
+  %s reword main..HEAD
+
+  # Copy the built-in commit prompt into .git-auto-commit/templates to edit it:
+  %s template eject prompt/commit.tmpl
+
+  # Generate a message whenever "git commit" is run without one:
+  %s hook install
 
 Options:
 `,
-			ProgramName, Version, RepoURL, os.Args[0], os.Args[0],
+			ProgramName, Version, RepoURL,
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
+			os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0],
 		)
 		pflag.PrintDefaults()
 	}
@@ -76,27 +119,85 @@ Options:
 	)
 	pflag.BoolVarP(
 		&cli.Yes, "yes", "y", false,
-		"Commits changes with the suggested message without prompting.",
+		"Commits changes with the suggested message without prompting to accept, edit, or regenerate it.",
 	)
 	pflag.StringVarP(
 		&cli.Message, "message", "m", "",
 		"Adds extra context for the LLM (why the change was made).",
 	)
+	pflag.BoolVar(
+		&cli.Amend, "amend", false,
+		"Regenerates the message for HEAD from all of its changes and amends it.",
+	)
+	pflag.BoolVar(
+		&cli.Split, "split", false,
+		"Splits the staged changes into several focused commits.",
+	)
+	pflag.IntVar(
+		&cli.Candidates, "candidates", 1,
+		fmt.Sprintf("Generates N (up to %d) alternative commit messages to pick from.", git_auto_commit.MaxCandidates),
+	)
+	pflag.BoolVar(
+		&cli.DryRun, "dry-run", false,
+		"Prints the generated message instead of committing.",
+	)
+	pflag.BoolVar(
+		&cli.DryRun, "print", false,
+		"Same as --dry-run.",
+	)
+	pflag.StringVar(
+		&cli.Output, "output", "",
+		"Writes the generated message to a file instead of committing.",
+	)
+	pflag.StringVar(
+		&cli.FromDiff, "from-diff", "",
+		"Prints a message for the unified diff in a file, or stdin for \"-\", instead of the staged changes.",
+	)
+	pflag.StringVar(
+		&cli.FromRange, "from-range", "",
+		"Prints a message for the diff of a revision range, such as main...HEAD, instead of the staged changes.",
+	)
+	pflag.BoolVar(
+		&cli.JSON, "json", false,
+		"Prints the generated message as JSON, with the model, token usage, and timings, instead of committing.",
+	)
 
 	// 4. Parse the pflags *once*.
 	pflag.Parse()
 
-	// 5. Any leftover arguments after pflag.Parse() become commit args.
+	// 5. Any leftover arguments after pflag.Parse() become commit args, unless
+	//    they start with a subcommand before any "--".
 	commitArgs := pflag.Args()
+	var subcommand []string
+	isSubcommand := len(commitArgs) > 0 && slices.Contains(subcommands, commitArgs[0])
+	if dash := pflag.CommandLine.ArgsLenAtDash(); dash != 0 && isSubcommand {
+		if dash < 0 {
+			dash = len(commitArgs)
+		}
+		subcommand, commitArgs = commitArgs[:dash], commitArgs[dash:]
+	}
 
 	if showVer {
 		fmt.Printf("%s %s\n", ProgramName, Version)
 		os.Exit(0)
 	}
 
+	if cli.Candidates > git_auto_commit.MaxCandidates {
+		fmt.Fprintf(os.Stderr, "--candidates must be at most %d\n", git_auto_commit.MaxCandidates)
+		os.Exit(2)
+	}
+
+	// Git runs every commit through the hook, so its errors are reported but
+	// never stop the commit.
+	inHook := len(subcommand) > 1 && subcommand[0] == "hook" && subcommand[1] == "run"
+
 	// 6. Load our layered config from default, git config, environment, and pflags.
 	cfg, err := config.Load()
 	if err != nil {
+		if inHook {
+			fmt.Fprintf(os.Stderr, "git-auto-commit: failed to load configuration: %v\n", err)
+			return
+		}
 		log.Fatalw("failed to load configuration", "error", err)
 	}
 
@@ -119,18 +220,114 @@ Options:
 	}
 	log.SetLevel(logLevel)
 
+	// Let the repository's and the user's templates override the built-in ones.
+	template.SetDirs(git_auto_commit.TemplateDirs()...)
+
 	// Create git_auto_commit.Config from our loaded config and CLI flags
 	commitConfig := &git_auto_commit.Config{
-		Config:    cfg,
-		Verbose:   cli.Verbose,
-		Yes:       cli.Yes,
-		Message:   cli.Message,
-		ExtraArgs: commitArgs,
+		Config:     cfg,
+		Verbose:    cli.Verbose,
+		Yes:        cli.Yes,
+		Message:    cli.Message,
+		Amend:      cli.Amend,
+		Split:      cli.Split,
+		Candidates: cli.Candidates,
+		DryRun:     cli.DryRun || cli.Output != "" || cli.JSON,
+		Output:     cli.Output,
+		JSON:       cli.JSON,
+		FromDiff:   cli.FromDiff,
+		FromRange:  cli.FromRange,
+		ExtraArgs:  commitArgs,
+	}
+	log.Debugw("commitConfig", "commitConfig", commitConfig)
+
+	// Run the subcommand or auto-commit logic
+	if len(subcommand) > 0 && subcommand[0] == "template" {
+		runTemplate(subcommand[1:])
+		return
+	}
+	if len(subcommand) > 0 && subcommand[0] == "hook" {
+		runHook(commitConfig, subcommand[1:])
+		return
+	}
+
+	// Catch mistakes in template overrides before doing any work.
+	if err := template.Validate(); err != nil {
+		log.Fatalw("invalid template", "error", err)
+	}
+	if len(subcommand) > 0 {
+		if len(subcommand) != 2 {
+			fmt.Fprintf(os.Stderr, "usage: %s reword [options] <range>\n", os.Args[0])
+			os.Exit(2)
+		}
+		if err := git_auto_commit.Reword(context.Background(), commitConfig, subcommand[1]); err != nil {
+			if errors.Is(err, git_auto_commit.ErrSecretsFound) {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			log.Fatalw("failed to reword commits", "error", err)
+		}
+		return
 	}
-	log.Infow("commitConfig", "commitConfig", commitConfig)
 
-	// Run the auto-commit logic
 	if err := git_auto_commit.AutoCommit(context.Background(), commitConfig); err != nil {
+		if errors.Is(err, git_auto_commit.ErrAborted) {
+			fmt.Fprintln(os.Stderr, "Aborted.")
+			os.Exit(1)
+		}
+		if errors.Is(err, git_auto_commit.ErrSecretsFound) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		log.Fatalw("failed to auto-commit", "error", err)
 	}
 }
+
+// runTemplate runs `template list` or `template eject <name>...`.
+func runTemplate(args []string) {
+	var err error
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		err = git_auto_commit.ListTemplates(os.Stdout)
+	case len(args) >= 1 && args[0] == "eject":
+		err = git_auto_commit.EjectTemplates(os.Stdout, args[1:]...)
+	default:
+		fmt.Fprintf(os.Stderr, "usage: %s template list|eject <name>...\n", os.Args[0])
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runHook runs `hook install`, `hook uninstall`, or `hook run <file> [<source>
+// [<commit>]]`, which Git calls as the prepare-commit-msg hook.
+func runHook(config *git_auto_commit.Config, args []string) {
+	var err error
+	switch {
+	case len(args) == 1 && args[0] == "install":
+		err = git_auto_commit.InstallHook(os.Stdout)
+	case len(args) == 1 && args[0] == "uninstall":
+		err = git_auto_commit.UninstallHook(os.Stdout)
+	case len(args) >= 2 && len(args) <= 4 && args[0] == "run":
+		var source string
+		if len(args) > 2 {
+			source = args[2]
+		}
+		err = git_auto_commit.RunHook(context.Background(), config, args[1], source)
+		if err != nil && !errors.Is(err, git_auto_commit.ErrSecretsFound) {
+			// Don't stand in the way of the commit; the message can still be
+			// written by hand.
+			fmt.Fprintf(os.Stderr, "git-auto-commit: %v\n", err)
+			return
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: %s hook install|uninstall|run <file> [<source> [<commit>]]\n", os.Args[0])
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
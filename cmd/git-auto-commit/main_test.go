@@ -2,7 +2,9 @@ package main_test
 
 import (
 	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -35,3 +37,74 @@ var _ = Describe("Integration with main.go binary", func() {
 		Expect(stderrBuf.String()).To(BeEmpty())
 	})
 })
+
+var _ = Describe("--candidates", func() {
+	It("rejects more than the maximum before doing any work", func() {
+		cmd := exec.Command("go", "run", "./main.go", "--candidates", "100")
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+
+		// `go run` exits with 1 whatever the program's exit code was.
+		_, ok := cmd.Run().(*exec.ExitError)
+		Expect(ok).To(BeTrue(), "Expected a non-zero exit code.")
+		Expect(stderr.String()).To(ContainSubstring("--candidates must be at most 10"))
+	})
+})
+
+var _ = Describe("hook run", func() {
+	var (
+		bin  string
+		repo string
+		msg  string
+	)
+
+	// run runs the binary in the repository as the prepare-commit-msg hook
+	// would, and returns its exit code and stderr.
+	run := func(env ...string) (int, string) {
+		cmd := exec.Command(bin, "hook", "run", msg)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), env...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+
+		err := cmd.Run()
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return exitError.ExitCode(), stderr.String()
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return 0, stderr.String()
+	}
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		bin = filepath.Join(dir, "git-auto-commit")
+		Expect(exec.Command("go", "build", "-o", bin, ".").Run()).To(Succeed())
+
+		repo = filepath.Join(dir, "repo")
+		Expect(exec.Command("git", "init", "-q", repo).Run()).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repo, "hello.txt"), []byte("hello\n"), 0o644)).To(Succeed())
+		add := exec.Command("git", "add", "hello.txt")
+		add.Dir = repo
+		Expect(add.Run()).To(Succeed())
+
+		msg = filepath.Join(repo, ".git", "COMMIT_EDITMSG")
+		Expect(os.WriteFile(msg, []byte("\n# Please enter the commit message.\n"), 0o644)).To(Succeed())
+	})
+
+	It("doesn't stop the commit when a template override is broken", func() {
+		dir := filepath.Join(repo, ".git-auto-commit", "templates", "prompt")
+		Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "commit.tmpl"), []byte("{{.Staged"), 0o644)).To(Succeed())
+
+		code, stderr := run("OPENAI_API_KEY=test")
+		Expect(code).To(Equal(0))
+		Expect(stderr).To(ContainSubstring("git-auto-commit:"))
+		Expect(stderr).To(ContainSubstring("commit.tmpl"))
+	})
+
+	It("doesn't stop the commit when the configuration is invalid", func() {
+		code, stderr := run("GIT_AUTO_COMMIT_TOKEN_BUDGET=lots")
+		Expect(code).To(Equal(0))
+		Expect(stderr).To(ContainSubstring("git-auto-commit: failed to load configuration"))
+	})
+})
@@ -9,6 +9,7 @@ import (
 
 	git_auto_commit "github.com/ivy/git-auto-commit"
 	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/template"
 	"github.com/ivy/git-auto-commit/util/log"
 )
 
@@ -31,7 +32,7 @@ type CLIFlags struct {
 
 func main() {
 	// 1. Initialize the config package so it can register pflags
-	//    for --provider, --model, and --openai-key.
+	//    for --provider, --model, and the API key flags.
 	config.Init()
 
 	// 2. Local flags for this CLI.
@@ -119,6 +120,12 @@ Options:
 	}
 	log.SetLevel(logLevel)
 
+	// Let the repository's and the user's templates override the built-in ones.
+	template.SetDirs(git_auto_commit.TemplateDirs()...)
+	if err := template.Validate(); err != nil {
+		log.Fatalw("invalid template", "error", err)
+	}
+
 	// Create git_auto_commit.Config from our loaded config and CLI flags
 	prConfig := &git_auto_commit.Config{
 		Config:    cfg,
@@ -127,7 +134,7 @@ Options:
 		Message:   cli.Message,
 		ExtraArgs: commitArgs,
 	}
-	log.Infow("commitConfig", "commitConfig", prConfig)
+	log.Debugw("commitConfig", "commitConfig", prConfig)
 
 	// Run the auto-commit logic
 	if err := git_auto_commit.AutoPullRequest(context.Background(), prConfig); err != nil {
@@ -3,8 +3,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	stdexec "os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/Netflix/go-env"
@@ -17,24 +20,129 @@ import (
 // Git config, environment variables, or command-line flags, in ascending order
 // of priority.
 type Config struct {
-	// Provider denotes the AI provider to use, such as "openai" or "anthropic".
-	// By default, this is set to "openai".
+	// Provider denotes the AI provider to use, such as "openai", "anthropic",
+	// "azure", or "ollama". By default, this is set to "openai".
 	Provider string `env:"GIT_AUTO_COMMIT_PROVIDER"`
 
 	// Model specifies the AI model to use, for example "gpt-4o-mini".
-	// By default, this is set to "gpt-4o-mini".
+	// By default, this is set to the provider's entry in DefaultModels.
 	Model string `env:"GIT_AUTO_COMMIT_MODEL"`
 
 	// OpenAIAPIKey stores the OpenAI token for authentication. This field can
 	// only be set via environment variables or pflags, and not from Git config,
-	// to avoid checking secrets into Git.
-	OpenAIAPIKey string `env:"OPENAI_API_KEY"`
+	// to avoid checking secrets into Git. It's left out when the Config is
+	// encoded, so that it never shows up in logs.
+	OpenAIAPIKey string `env:"OPENAI_API_KEY" json:"-"`
+
+	// AnthropicAPIKey stores the Anthropic token for authentication. Like
+	// OpenAIAPIKey, it is never read from Git config or logged.
+	AnthropicAPIKey string `env:"ANTHROPIC_API_KEY" json:"-"`
+
+	// BaseURL points the "openai" provider at an OpenAI-compatible endpoint,
+	// or the "anthropic" provider at an Anthropic-compatible one, such as a
+	// self-hosted gateway or proxy. By default, the provider's official API is
+	// used.
+	BaseURL string `env:"GIT_AUTO_COMMIT_BASE_URL"`
+
+	// Headers are extra HTTP headers, in "Name: value" form, sent with every
+	// request to the provider. In Git config, each header is a separate
+	// `auto-commit.header` entry; in the environment, they're separated by "|".
+	// Gateways often authenticate with a header, so they're never logged.
+	Headers []string `env:"GIT_AUTO_COMMIT_HEADERS" json:"-"`
+
+	// Organization is the OpenAI organization ID sent with each request.
+	Organization string `env:"OPENAI_ORG_ID"`
+
+	// Project is the OpenAI project ID sent with each request.
+	Project string `env:"OPENAI_PROJECT_ID"`
+
+	// AzureEndpoint is the Azure OpenAI resource endpoint used by the "azure"
+	// provider, for example "https://example.openai.azure.com".
+	AzureEndpoint string `env:"AZURE_OPENAI_ENDPOINT"`
+
+	// AzureDeployment names the Azure OpenAI deployment to use. By default,
+	// Model is used as the deployment name.
+	AzureDeployment string `env:"GIT_AUTO_COMMIT_AZURE_DEPLOYMENT"`
+
+	// AzureAPIVersion is the Azure OpenAI API version sent with each request.
+	// By default, this is set to "2024-10-21".
+	AzureAPIVersion string `env:"OPENAI_API_VERSION"`
+
+	// AzureAPIKey stores the Azure OpenAI key for authentication. Like
+	// OpenAIAPIKey, it is never read from Git config or logged.
+	AzureAPIKey string `env:"AZURE_OPENAI_API_KEY" json:"-"`
+
+	// OllamaHost is the address of the Ollama server used by the "ollama"
+	// provider. By default, this is set to "http://localhost:11434".
+	OllamaHost string `env:"OLLAMA_HOST"`
+
+	// Exclude lists gitignore-style patterns of paths whose contents are
+	// never sent to the provider, such as lock files or secrets. Excluded
+	// files are still mentioned by name. In Git config, each pattern is a
+	// separate `auto-commit.exclude` entry; in the environment, they're
+	// separated by "|".
+	Exclude []string `env:"GIT_AUTO_COMMIT_EXCLUDE"`
+
+	// Secrets controls what happens when likely secrets are found in a diff:
+	// "redact" masks them before the diff is sent, "abort" stops with a list
+	// of where they were found, and "off" sends the diff as is. By default,
+	// this is set to "redact".
+	Secrets string `env:"GIT_AUTO_COMMIT_SECRETS"`
+
+	// SecretPatterns are extra regular expressions that match secrets, in
+	// addition to the built-in detectors. In Git config, each pattern is a
+	// separate `auto-commit.secret-pattern` entry; in the environment, they're
+	// separated by "|".
+	SecretPatterns []string `env:"GIT_AUTO_COMMIT_SECRET_PATTERNS"`
+
+	// TokenBudget is the approximate number of tokens the staged diff may use
+	// in a prompt. By default, it's derived from the model's context window.
+	TokenBudget int `env:"GIT_AUTO_COMMIT_TOKEN_BUDGET"`
+
+	// DiffStrategy controls how a diff over TokenBudget is shrunk: "truncate"
+	// reduces large and generated files to stat lines, "summarize" has the
+	// model summarize each file before writing the message, and "full" sends
+	// the diff as is. By default, this is set to "truncate".
+	DiffStrategy string `env:"GIT_AUTO_COMMIT_DIFF_STRATEGY"`
+
+	// Format selects the commit message format: "classic" for a free-form
+	// summary and body, or "conventional" for Conventional Commits. By
+	// default, this is set to "classic".
+	Format string `env:"GIT_AUTO_COMMIT_FORMAT"`
+
+	// LintIgnore lists the names of lint rules to skip when checking
+	// generated messages, or "all" to skip linting entirely. In Git config,
+	// each rule is a separate `auto-commit.lint-ignore` entry; in the
+	// environment, they're separated by "|".
+	LintIgnore []string `env:"GIT_AUTO_COMMIT_LINT_IGNORE"`
+
+	// SubjectLength is the maximum length of a message's subject line. By
+	// default, it's 50 for the classic format and 72 for Conventional
+	// Commits, whose headers spend characters on the type and scope.
+	SubjectLength int `env:"GIT_AUTO_COMMIT_SUBJECT_LENGTH"`
+
+	// BodyWidth is the column that message bodies are wrapped at. By default,
+	// this is set to 72.
+	BodyWidth int `env:"GIT_AUTO_COMMIT_BODY_WIDTH"`
+
+	// StyleSamples is the number of recent commits whose messages are sampled
+	// to learn the repository's style, such as prefixes, ticket references,
+	// and tense. Zero disables style learning. By default, this is set to 50.
+	StyleSamples int `env:"GIT_AUTO_COMMIT_STYLE_SAMPLES"`
 
 	// LogLevel configures the log verbosity.
 	LogLevel string `env:"GIT_AUTO_COMMIT_LOG_LEVEL"`
 }
 
-// providerFlag, modelFlag, and openAIKeyFlag retain the values passed via the
+// DefaultModels maps each provider to the model used when none is configured.
+var DefaultModels = map[string]string{
+	"openai":    "gpt-4o-mini",
+	"anthropic": "claude-3-5-haiku-latest",
+	"azure":     "gpt-4o-mini",
+	"ollama":    "llama3.2",
+}
+
+// providerFlag, modelFlag, and the API key flags retain the values passed via the
 // corresponding pflags. They are defined here and wired up in Init() so that
 // help text is available before Load() is called.
 var (
@@ -47,6 +155,36 @@ var (
 	// openAIKeyFlag holds the value of --openai-key.
 	openAIKeyFlag *string
 
+	// anthropicKeyFlag holds the value of --anthropic-key.
+	anthropicKeyFlag *string
+
+	// azureKeyFlag holds the value of --azure-key.
+	azureKeyFlag *string
+
+	// baseURLFlag holds the value of --base-url.
+	baseURLFlag *string
+
+	// headerFlag holds the values of --header.
+	headerFlag *[]string
+
+	// excludeFlag holds the values of --exclude.
+	excludeFlag *[]string
+
+	// secretsFlag holds the value of --secrets.
+	secretsFlag *string
+
+	// tokenBudgetFlag holds the value of --token-budget.
+	tokenBudgetFlag *int
+
+	// diffStrategyFlag holds the value of --diff-strategy.
+	diffStrategyFlag *string
+
+	// formatFlag holds the value of --format.
+	formatFlag *string
+
+	// lintIgnoreFlag holds the values of --lint-ignore.
+	lintIgnoreFlag *[]string
+
 	// logLevel holds the value of --log-level.
 	logLevel *string
 )
@@ -75,6 +213,36 @@ func Init() {
 	openAIKeyFlag = pflag.String("openai-key", "",
 		"OpenAI API key (overrides env)")
 
+	anthropicKeyFlag = pflag.String("anthropic-key", "",
+		"Anthropic API key (overrides env)")
+
+	azureKeyFlag = pflag.String("azure-key", "",
+		"Azure OpenAI API key (overrides env)")
+
+	baseURLFlag = pflag.String("base-url", "",
+		"OpenAI- or Anthropic-compatible API base URL (overrides env or Git config)")
+
+	headerFlag = pflag.StringArray("header", nil,
+		`Extra HTTP header as "Name: value", may be repeated (overrides env or Git config)`)
+
+	excludeFlag = pflag.StringArray("exclude", nil,
+		"Gitignore-style pattern of paths to leave out of prompts, may be repeated (overrides env or Git config)")
+
+	secretsFlag = pflag.String("secrets", "",
+		`What to do with likely secrets in diffs: "redact", "abort", or "off" (overrides env or Git config)`)
+
+	tokenBudgetFlag = pflag.Int("token-budget", 0,
+		"Approximate token limit for the diff in a prompt (overrides env or Git config)")
+
+	diffStrategyFlag = pflag.String("diff-strategy", "",
+		`How to shrink diffs over the token budget: "truncate", "summarize", or "full" (overrides env or Git config)`)
+
+	formatFlag = pflag.String("format", "",
+		`Commit message format: "classic" or "conventional" (overrides env or Git config)`)
+
+	lintIgnoreFlag = pflag.StringArray("lint-ignore", nil,
+		`Lint rule to skip when checking generated messages, or "all", may be repeated (overrides env or Git config)`)
+
 	logLevel = pflag.String("log-level", "",
 		"Log level (overrides env)")
 }
@@ -85,22 +253,48 @@ func Init() {
 //  3. Environment variables (via go-env),
 //  4. pflag values (highest priority).
 //
+// If no layer sets a model, the provider's entry in DefaultModels is used.
+//
 // It returns a fully populated Config instance, or an error if environment
 // unmarshaling fails. Git config is merely logged upon failure, not returned
 // as an error.
 func Load() (*Config, error) {
 	// 1) Built-in defaults.
 	cfg := &Config{
-		Provider: "openai",
-		Model:    "gpt-4o-mini",
-		LogLevel: "info",
+		Provider:        "openai",
+		AzureAPIVersion: "2024-10-21",
+		OllamaHost:      "http://localhost:11434",
+		Secrets:         "redact",
+		DiffStrategy:    "truncate",
+		Format:          "classic",
+		BodyWidth:       72,
+		StyleSamples:    50,
+		LogLevel:        "info",
 	}
 
 	// 2) Git config (non-secret values only).
 	getGitConfigValue("auto-commit.provider", &cfg.Provider)
 	getGitConfigValue("auto-commit.model", &cfg.Model)
+	getGitConfigValue("auto-commit.base-url", &cfg.BaseURL)
+	getGitConfigValues("auto-commit.header", &cfg.Headers)
+	getGitConfigValue("auto-commit.organization", &cfg.Organization)
+	getGitConfigValue("auto-commit.project", &cfg.Project)
+	getGitConfigValue("auto-commit.azure-endpoint", &cfg.AzureEndpoint)
+	getGitConfigValue("auto-commit.azure-deployment", &cfg.AzureDeployment)
+	getGitConfigValue("auto-commit.azure-api-version", &cfg.AzureAPIVersion)
+	getGitConfigValue("auto-commit.ollama-host", &cfg.OllamaHost)
+	getGitConfigValues("auto-commit.exclude", &cfg.Exclude)
+	getGitConfigValue("auto-commit.secrets", &cfg.Secrets)
+	getGitConfigValues("auto-commit.secret-pattern", &cfg.SecretPatterns)
+	getGitConfigInt("auto-commit.token-budget", &cfg.TokenBudget)
+	getGitConfigValue("auto-commit.diff-strategy", &cfg.DiffStrategy)
+	getGitConfigValue("auto-commit.format", &cfg.Format)
+	getGitConfigValues("auto-commit.lint-ignore", &cfg.LintIgnore)
+	getGitConfigInt("auto-commit.subject-length", &cfg.SubjectLength)
+	getGitConfigInt("auto-commit.body-width", &cfg.BodyWidth)
+	getGitConfigInt("auto-commit.style-samples", &cfg.StyleSamples)
 	getGitConfigValue("auto-commit.log-level", &cfg.LogLevel)
-	// We intentionally do not read OpenAIAPIKey from Git config.
+	// We intentionally do not read API keys from Git config.
 
 	// 3) Environment variables.
 	if _, err := env.UnmarshalFromEnviron(cfg); err != nil {
@@ -117,17 +311,52 @@ func Load() (*Config, error) {
 	if *openAIKeyFlag != "" {
 		cfg.OpenAIAPIKey = *openAIKeyFlag
 	}
+	if *anthropicKeyFlag != "" {
+		cfg.AnthropicAPIKey = *anthropicKeyFlag
+	}
+	if *azureKeyFlag != "" {
+		cfg.AzureAPIKey = *azureKeyFlag
+	}
+	if *baseURLFlag != "" {
+		cfg.BaseURL = *baseURLFlag
+	}
+	if len(*headerFlag) > 0 {
+		cfg.Headers = *headerFlag
+	}
+	if len(*excludeFlag) > 0 {
+		cfg.Exclude = *excludeFlag
+	}
+	if *secretsFlag != "" {
+		cfg.Secrets = *secretsFlag
+	}
+	if *tokenBudgetFlag != 0 {
+		cfg.TokenBudget = *tokenBudgetFlag
+	}
+	if *diffStrategyFlag != "" {
+		cfg.DiffStrategy = *diffStrategyFlag
+	}
+	if *formatFlag != "" {
+		cfg.Format = *formatFlag
+	}
+	if len(*lintIgnoreFlag) > 0 {
+		cfg.LintIgnore = *lintIgnoreFlag
+	}
 	if *logLevel != "" {
 		cfg.LogLevel = *logLevel
 	}
 
+	// The default model depends on the provider, so it's resolved last.
+	if cfg.Model == "" {
+		cfg.Model = DefaultModels[cfg.Provider]
+	}
+
 	return cfg, nil
 }
 
 // getGitConfigValue runs `git config --get <key>` to read a single
 // configuration value from Git, assigning it to out if successful.
-// If any error occurs (like key not found), the error is logged but
-// not returned, so that config loading can continue gracefully.
+// Unset keys are skipped quietly. Any other error is logged but not
+// returned, so that config loading can continue gracefully.
 //
 // Example:
 //
@@ -135,6 +364,9 @@ func Load() (*Config, error) {
 func getGitConfigValue(key string, out *string) {
 	raw, err := exec.Command("git", "config", "--get", key).Output()
 	if err != nil {
+		if isUnset(err) {
+			return
+		}
 		log.Printf("Error reading git config for %q: %v", key, err)
 		return
 	}
@@ -143,3 +375,60 @@ func getGitConfigValue(key string, out *string) {
 		*out = trimmed
 	}
 }
+
+// isUnset reports whether err is `git config` exiting with status 1, which
+// means the key isn't set rather than that something went wrong.
+func isUnset(err error) bool {
+	var exitErr *stdexec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1
+}
+
+// getGitConfigValues runs `git config --get-all <key>` to read a multi-valued
+// configuration entry from Git, assigning the values to out if any are found.
+// Like getGitConfigValue, errors are logged but not returned.
+//
+// Example:
+//
+//	getGitConfigValues("auto-commit.header", &cfg.Headers)
+func getGitConfigValues(key string, out *[]string) {
+	raw, err := exec.Command("git", "config", "--get-all", key).Output()
+	if err != nil {
+		if isUnset(err) {
+			return
+		}
+		log.Printf("Error reading git config for %q: %v", key, err)
+		return
+	}
+
+	var values []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	if len(values) > 0 {
+		*out = values
+	}
+}
+
+// getGitConfigInt reads a single integer value from Git like
+// getGitConfigValue, assigning it to out if it's set and valid. Invalid values
+// are logged and ignored.
+//
+// Example:
+//
+//	getGitConfigInt("auto-commit.token-budget", &cfg.TokenBudget)
+func getGitConfigInt(key string, out *int) {
+	var raw string
+	getGitConfigValue(key, &raw)
+	if raw == "" {
+		return
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Printf("Invalid integer in git config for %q: %v", key, err)
+		return
+	}
+	*out = n
+}
@@ -1,8 +1,11 @@
 package config_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"os"
+	stdexec "os/exec"
 	"strings"
 	"testing"
 
@@ -33,6 +36,16 @@ var _ = Describe("Config", func() {
 		// Save the current environment.
 		origEnv = os.Environ()
 
+		// Clear secrets that may be set in the developer's environment.
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("ANTHROPIC_API_KEY")
+		os.Unsetenv("OLLAMA_HOST")
+		os.Unsetenv("OPENAI_ORG_ID")
+		os.Unsetenv("OPENAI_PROJECT_ID")
+		os.Unsetenv("AZURE_OPENAI_API_KEY")
+		os.Unsetenv("AZURE_OPENAI_ENDPOINT")
+		os.Unsetenv("OPENAI_API_VERSION")
+
 		// Save original execCommand.
 		originalExecCommand = exec.GetCommand()
 
@@ -80,8 +93,56 @@ var _ = Describe("Config", func() {
 			Expect(cfg.Provider).To(Equal("openai"))
 			Expect(cfg.Model).To(Equal("gpt-4o-mini"))
 			Expect(cfg.OpenAIAPIKey).To(Equal(""))
+			Expect(cfg.AnthropicAPIKey).To(Equal(""))
+			Expect(cfg.OllamaHost).To(Equal("http://localhost:11434"))
+			Expect(cfg.BaseURL).To(Equal(""))
+			Expect(cfg.Headers).To(BeEmpty())
+			Expect(cfg.AzureAPIVersion).To(Equal("2024-10-21"))
+			Expect(cfg.AzureAPIKey).To(Equal(""))
+			Expect(cfg.Exclude).To(BeEmpty())
+			Expect(cfg.Secrets).To(Equal("redact"))
+			Expect(cfg.SecretPatterns).To(BeEmpty())
+			Expect(cfg.TokenBudget).To(Equal(0))
+			Expect(cfg.DiffStrategy).To(Equal("truncate"))
+			Expect(cfg.Format).To(Equal("classic"))
+			Expect(cfg.LintIgnore).To(BeEmpty())
+			Expect(cfg.SubjectLength).To(Equal(0))
+			Expect(cfg.BodyWidth).To(Equal(72))
+			Expect(cfg.StyleSamples).To(Equal(50))
 			Expect(cfg.LogLevel).To(Equal("info"))
 		})
+
+		It("doesn't log keys that are unset", func() {
+			// `git config` exits with status 1 when a key isn't set.
+			unset := stdexec.Command("sh", "-c", "exit 1").Run()
+			exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+				return exec.NewMockCmd(nil, unset)
+			})
+
+			var logged strings.Builder
+			log.SetOutput(&logged)
+			DeferCleanup(log.SetOutput, os.Stderr)
+
+			_ = flagSet.Parse([]string{})
+
+			_, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logged.String()).To(BeEmpty())
+		})
+
+		It("uses the default model of the selected provider", func() {
+			exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+				return exec.NewMockCmd([]byte(""), fmt.Errorf("not found"))
+			})
+			os.Setenv("GIT_AUTO_COMMIT_PROVIDER", "anthropic")
+
+			_ = flagSet.Parse([]string{})
+
+			cfg, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Provider).To(Equal("anthropic"))
+			Expect(cfg.Model).To(Equal(config.DefaultModels["anthropic"]))
+		})
 	})
 
 	Context("when Git config provides values", func() {
@@ -102,10 +163,63 @@ var _ = Describe("Config", func() {
 			// confirm both fields changed from default:
 			Expect(cfg.Provider).To(Equal("anthropic"))
 			Expect(cfg.Model).To(Equal("anthropic"))
+			Expect(cfg.OllamaHost).To(Equal("anthropic"))
+			Expect(cfg.BaseURL).To(Equal("anthropic"))
+			Expect(cfg.Organization).To(Equal("anthropic"))
+			Expect(cfg.Project).To(Equal("anthropic"))
+			Expect(cfg.AzureEndpoint).To(Equal("anthropic"))
+			Expect(cfg.AzureDeployment).To(Equal("anthropic"))
+			Expect(cfg.AzureAPIVersion).To(Equal("anthropic"))
+			Expect(cfg.Secrets).To(Equal("anthropic"))
+			Expect(cfg.DiffStrategy).To(Equal("anthropic"))
+			Expect(cfg.Format).To(Equal("anthropic"))
 			Expect(cfg.LogLevel).To(Equal("anthropic"))
 
-			// Secret is not read from Git, remains default:
+			// Secrets are not read from Git, remain default:
 			Expect(cfg.OpenAIAPIKey).To(Equal(""))
+			Expect(cfg.AnthropicAPIKey).To(Equal(""))
+			Expect(cfg.AzureAPIKey).To(Equal(""))
+		})
+	})
+
+	Context("when Git config provides multiple headers", func() {
+		It("reads every value of the multi-valued key", func() {
+			exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+				if arg[len(arg)-1] == "auto-commit.header" {
+					Expect(arg).To(ContainElement("--get-all"))
+					return exec.NewMockCmd([]byte("X-Team: platform\nX-Trace: abc\n"), nil)
+				}
+				return exec.NewMockCmd([]byte(""), fmt.Errorf("not found"))
+			})
+
+			_ = flagSet.Parse([]string{})
+
+			cfg, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Headers).To(Equal([]string{"X-Team: platform", "X-Trace: abc"}))
+		})
+	})
+
+	Context("when Git config provides a token budget", func() {
+		It("parses valid integers and ignores invalid ones", func() {
+			budget := "12000\n"
+			exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+				if arg[len(arg)-1] == "auto-commit.token-budget" {
+					return exec.NewMockCmd([]byte(budget), nil)
+				}
+				return exec.NewMockCmd([]byte(""), fmt.Errorf("not found"))
+			})
+
+			_ = flagSet.Parse([]string{})
+
+			cfg, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.TokenBudget).To(Equal(12000))
+
+			budget = "lots\n"
+			cfg, err = config.Load()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.TokenBudget).To(Equal(0))
 		})
 	})
 
@@ -121,6 +235,21 @@ var _ = Describe("Config", func() {
 			os.Setenv("GIT_AUTO_COMMIT_MODEL", "env-model")
 			os.Setenv("GIT_AUTO_COMMIT_LOG_LEVEL", "env-log-level")
 			os.Setenv("OPENAI_API_KEY", "env-secret")
+			os.Setenv("ANTHROPIC_API_KEY", "env-anthropic-secret")
+			os.Setenv("GIT_AUTO_COMMIT_BASE_URL", "https://env.example.com/v1")
+			os.Setenv("GIT_AUTO_COMMIT_HEADERS", "X-Env: 1|X-Other: 2")
+			os.Setenv("AZURE_OPENAI_API_KEY", "env-azure-secret")
+			os.Setenv("AZURE_OPENAI_ENDPOINT", "https://env.openai.azure.com")
+			os.Setenv("GIT_AUTO_COMMIT_EXCLUDE", "*.lock|dist/")
+			os.Setenv("GIT_AUTO_COMMIT_SECRETS", "abort")
+			os.Setenv("GIT_AUTO_COMMIT_SECRET_PATTERNS", `ACME-\d+|corp_[a-z]+`)
+			os.Setenv("GIT_AUTO_COMMIT_TOKEN_BUDGET", "5000")
+			os.Setenv("GIT_AUTO_COMMIT_DIFF_STRATEGY", "summarize")
+			os.Setenv("GIT_AUTO_COMMIT_FORMAT", "conventional")
+			os.Setenv("GIT_AUTO_COMMIT_LINT_IGNORE", "imperative|body-wrap")
+			os.Setenv("GIT_AUTO_COMMIT_SUBJECT_LENGTH", "60")
+			os.Setenv("GIT_AUTO_COMMIT_BODY_WIDTH", "80")
+			os.Setenv("GIT_AUTO_COMMIT_STYLE_SAMPLES", "0")
 
 			_ = flagSet.Parse([]string{})
 
@@ -132,6 +261,21 @@ var _ = Describe("Config", func() {
 			Expect(cfg.Model).To(Equal("env-model"))
 			Expect(cfg.LogLevel).To(Equal("env-log-level"))
 			Expect(cfg.OpenAIAPIKey).To(Equal("env-secret"))
+			Expect(cfg.AnthropicAPIKey).To(Equal("env-anthropic-secret"))
+			Expect(cfg.BaseURL).To(Equal("https://env.example.com/v1"))
+			Expect(cfg.Headers).To(Equal([]string{"X-Env: 1", "X-Other: 2"}))
+			Expect(cfg.AzureAPIKey).To(Equal("env-azure-secret"))
+			Expect(cfg.AzureEndpoint).To(Equal("https://env.openai.azure.com"))
+			Expect(cfg.Exclude).To(Equal([]string{"*.lock", "dist/"}))
+			Expect(cfg.Secrets).To(Equal("abort"))
+			Expect(cfg.SecretPatterns).To(Equal([]string{`ACME-\d+`, `corp_[a-z]+`}))
+			Expect(cfg.TokenBudget).To(Equal(5000))
+			Expect(cfg.DiffStrategy).To(Equal("summarize"))
+			Expect(cfg.Format).To(Equal("conventional"))
+			Expect(cfg.LintIgnore).To(Equal([]string{"imperative", "body-wrap"}))
+			Expect(cfg.SubjectLength).To(Equal(60))
+			Expect(cfg.BodyWidth).To(Equal(80))
+			Expect(cfg.StyleSamples).To(Equal(0))
 		})
 	})
 
@@ -154,6 +298,16 @@ var _ = Describe("Config", func() {
 				"--model=flag-model",
 				"--log-level=flag-log-level",
 				"--openai-key=flag-secret",
+				"--anthropic-key=flag-anthropic-secret",
+				"--base-url=https://flag.example.com/v1",
+				"--header=X-Flag: 1",
+				"--azure-key=flag-azure-secret",
+				"--exclude=go.sum",
+				"--secrets=off",
+				"--token-budget=9000",
+				"--diff-strategy=full",
+				"--format=conventional",
+				"--lint-ignore=all",
 			})
 			Expect(err).NotTo(HaveOccurred())
 
@@ -165,6 +319,33 @@ var _ = Describe("Config", func() {
 			Expect(cfg.Model).To(Equal("flag-model"))
 			Expect(cfg.LogLevel).To(Equal("flag-log-level"))
 			Expect(cfg.OpenAIAPIKey).To(Equal("flag-secret"))
+			Expect(cfg.AnthropicAPIKey).To(Equal("flag-anthropic-secret"))
+			Expect(cfg.BaseURL).To(Equal("https://flag.example.com/v1"))
+			Expect(cfg.Headers).To(Equal([]string{"X-Flag: 1"}))
+			Expect(cfg.AzureAPIKey).To(Equal("flag-azure-secret"))
+			Expect(cfg.Exclude).To(Equal([]string{"go.sum"}))
+			Expect(cfg.Secrets).To(Equal("off"))
+			Expect(cfg.TokenBudget).To(Equal(9000))
+			Expect(cfg.DiffStrategy).To(Equal("full"))
+			Expect(cfg.Format).To(Equal("conventional"))
+			Expect(cfg.LintIgnore).To(Equal([]string{"all"}))
+		})
+
+		It("leaves secrets out when encoded for logs", func() {
+			err := flagSet.Parse([]string{
+				"--openai-key=flag-secret",
+				"--anthropic-key=flag-anthropic-secret",
+				"--azure-key=flag-azure-secret",
+				"--header=Authorization: Bearer flag-header-secret",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg, err := config.Load()
+			Expect(err).NotTo(HaveOccurred())
+
+			encoded, err := json.Marshal(cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(encoded)).NotTo(ContainSubstring("secret"))
 		})
 
 		It("does not override if the flag is empty", func() {
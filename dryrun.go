@@ -0,0 +1,170 @@
+package git_auto_commit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ivy/git-auto-commit/provider"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// Result is what a dry run writes: the generated message, and what it took to
+// generate it.
+type Result struct {
+	// Message is the generated commit message.
+	Message string `json:"message"`
+
+	// Candidates are all of the generated messages when more than one was
+	// asked for. Message is the first of them.
+	Candidates []string `json:"candidates,omitempty"`
+
+	// Provider is the name of the provider the message was generated with.
+	Provider string `json:"provider"`
+
+	// Model is the model the message was generated with.
+	Model string `json:"model"`
+
+	// Usage counts the requests made and the tokens they used.
+	Usage Usage `json:"usage"`
+
+	// Timings measure how long the run and the requests took.
+	Timings Timings `json:"timings"`
+}
+
+// Usage counts the requests made to the provider and the tokens they used.
+// Providers that don't report usage count as zero tokens.
+type Usage struct {
+	Requests     int `json:"requests"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Timings measure how long a run took, in milliseconds.
+type Timings struct {
+	// TotalMS is the time from start to finish, including Git commands.
+	TotalMS int64 `json:"total_ms"`
+
+	// GenerationMS is the time spent waiting on the provider. Parallel
+	// requests are counted separately, so it may exceed TotalMS.
+	GenerationMS int64 `json:"generation_ms"`
+}
+
+// usageTally adds up the usage and duration of every request made for a
+// Config. Candidates are generated in parallel, so it's safe for concurrent
+// use.
+type usageTally struct {
+	mu      sync.Mutex
+	usage   Usage
+	elapsed time.Duration
+}
+
+// add records a completed request.
+func (t *usageTally) add(usage provider.Usage, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.usage.Requests++
+	t.usage.InputTokens += usage.InputTokens
+	t.usage.OutputTokens += usage.OutputTokens
+	t.elapsed += elapsed
+}
+
+// total returns the usage and duration of every request recorded so far.
+func (t *usageTally) total() (Usage, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage, t.elapsed
+}
+
+// track returns a Completer that records the usage of each request made
+// through completer.
+func (t *usageTally) track(completer provider.Completer) provider.Completer {
+	return &trackedCompleter{Completer: completer, tally: t}
+}
+
+// trackedCompleter is a Completer that records its requests in a tally.
+type trackedCompleter struct {
+	provider.Completer
+	tally *usageTally
+}
+
+func (c *trackedCompleter) Complete(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	start := time.Now()
+	resp, err := c.Completer.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.tally.add(resp.Usage, time.Since(start))
+	return resp, nil
+}
+
+// dryRun generates a message for the staged changes and writes it, rather
+// than committing. With config.Candidates, all of the candidates are
+// generated, and the first is written as the message.
+func dryRun(ctx context.Context, config *Config, staged string, start time.Time) error {
+	result := &Result{
+		Provider: config.Provider,
+		Model:    config.Model,
+	}
+
+	if config.Candidates < 2 {
+		message, err := GenerateCommitMessage(ctx, config, staged)
+		if err != nil {
+			return err
+		}
+		result.Message = message
+	} else {
+		candidates, err := GenerateCommitMessages(ctx, config, staged, config.Candidates)
+		if err != nil {
+			return err
+		}
+		result.Message, result.Candidates = candidates[0], candidates
+	}
+
+	usage, generation := config.usage.total()
+	result.Usage = usage
+	result.Timings = Timings{
+		TotalMS:      time.Since(start).Milliseconds(),
+		GenerationMS: generation.Milliseconds(),
+	}
+
+	return writeResult(config, result)
+}
+
+// writeResult writes the result to config.Output, or stdout if it's "" or
+// "-", as JSON if config.JSON is set and as the bare message otherwise.
+func writeResult(config *Config, result *Result) error {
+	if config.Output == "" || config.Output == "-" {
+		return encodeResult(os.Stdout, config.JSON, result)
+	}
+
+	f, err := os.Create(config.Output)
+	if err != nil {
+		log.Errorw("failed to create output file",
+			"path", config.Output,
+			"error", err)
+		return err
+	}
+	defer f.Close()
+
+	if err := encodeResult(f, config.JSON, result); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// encodeResult writes the result to w, as indented JSON or the bare message.
+func encodeResult(w io.Writer, asJSON bool, result *Result) error {
+	if !asJSON {
+		_, err := fmt.Fprintln(w, result.Message)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
@@ -0,0 +1,346 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/template"
+	"github.com/ivy/git-auto-commit/util/exec"
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+const (
+	scissors    = "------------------------ >8 ------------------------"
+	commentChar = "#"
+
+	// autoCommentChars are the characters core.commentChar=auto picks from,
+	// in order, as Git does.
+	autoCommentChars = "#;@!$%^&|:"
+)
+
+// Cleanup modes, as set by commit.cleanup.
+const (
+	cleanupStrip      = "strip"
+	cleanupWhitespace = "whitespace"
+	cleanupVerbatim   = "verbatim"
+	cleanupScissors   = "scissors"
+)
+
+// editSettings are the Git settings that shape the message the editor opens
+// with, so that it looks the way `git commit -v` would make it.
+type editSettings struct {
+	// commentChar starts the lines Git ignores, from core.commentChar.
+	commentChar string
+
+	// cleanup is how Git cleans up the edited message, from commit.cleanup.
+	cleanup string
+
+	// verbose is the number of diffs shown below the scissors, from
+	// commit.verbose: none, the staged changes, or the staged and then the
+	// unstaged changes.
+	verbose int
+}
+
+// readEditSettings reads the settings that apply to editing message. Unlike
+// `git commit`, the staged changes are shown unless commit.verbose is
+// explicitly turned off.
+func readEditSettings(message string) (*editSettings, error) {
+	settings := &editSettings{
+		commentChar: commentChar,
+		cleanup:     cleanupStrip,
+		verbose:     1,
+	}
+
+	var err error
+	if settings.commentChar, err = readCommentChar(message); err != nil {
+		return nil, err
+	}
+
+	cleanup, err := git.ConfigValue("commit.cleanup")
+	if err != nil {
+		return nil, err
+	}
+	switch cleanup {
+	case "", "default":
+	case cleanupStrip, cleanupWhitespace, cleanupVerbatim, cleanupScissors:
+		settings.cleanup = cleanup
+	default:
+		return nil, fmt.Errorf("invalid commit.cleanup mode %q", cleanup)
+	}
+
+	verbose, err := git.ConfigValue("commit.verbose", "--type=bool-or-int")
+	if err != nil {
+		return nil, err
+	}
+	switch verbose {
+	case "":
+	case "true":
+		settings.verbose = 1
+	case "false":
+		settings.verbose = 0
+	default:
+		if settings.verbose, err = strconv.Atoi(verbose); err != nil {
+			return nil, fmt.Errorf("invalid commit.verbose value %q", verbose)
+		}
+	}
+
+	return settings, nil
+}
+
+// readCommentChar returns the character that starts comment lines, from
+// core.commentChar.
+func readCommentChar(message string) (string, error) {
+	char, err := git.ConfigValue("core.commentChar")
+	switch {
+	case err != nil:
+		return "", err
+	case char == "":
+		return commentChar, nil
+	case char == "auto":
+		return pickCommentChar(message)
+	}
+	return char, nil
+}
+
+// pickCommentChar returns the first of autoCommentChars that doesn't start
+// any line of message, as core.commentChar=auto does.
+func pickCommentChar(message string) (string, error) {
+	candidates := autoCommentChars
+	for _, line := range strings.Split(message, "\n") {
+		if line != "" {
+			candidates = strings.ReplaceAll(candidates, line[:1], "")
+		}
+	}
+	if candidates == "" {
+		return "", errors.New("unable to select a comment character that is not used in the commit message")
+	}
+	return candidates[:1], nil
+}
+
+// commitTemplate returns the contents of the team's commit message template,
+// from commit.template, at most once per Config. It returns "" if there's
+// none or it can't be read.
+func commitTemplate(config *Config) string {
+	if config.template != nil {
+		return *config.template
+	}
+
+	var text string
+	path, err := git.ConfigValue("commit.template", "--type=path")
+	if err == nil && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			log.Warnw("failed to read commit.template, ignoring it",
+				"path", path,
+				"error", err)
+		}
+		text = string(b)
+	}
+	config.template = &text
+	return text
+}
+
+// templateComments returns the comment lines of a commit message template,
+// which are guidance for whoever writes the message, followed by a blank line.
+// It returns "" if there are none.
+func templateComments(tmpl, commentChar string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(tmpl, "\n") {
+		if strings.HasPrefix(line, commentChar) {
+			b.WriteString(line + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String() + "\n"
+}
+
+// comment writes text to w as comment lines, the way Git comments its status:
+// blank lines get only the comment character, and lines starting with a tab
+// aren't separated from it by a space.
+func (s *editSettings) comment(w io.Writer, text string) error {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && !strings.HasPrefix(line, "\t") {
+			line = " " + line
+		}
+		if _, err := io.WriteString(w, s.commentChar+line+"\n"); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// cutLine returns the scissors line, below which Git ignores everything.
+func (s *editSettings) cutLine() string {
+	return s.commentChar + " " + scissors + "\n"
+}
+
+// editMessage returns the text the editor opens with: the message, then the
+// comments from the team's template, if any, then the commented status and
+// instructions, and then any diffs below the scissors.
+func (s *editSettings) editMessage(message, tmpl, staged, status, unstaged string) (string, error) {
+	footer, err := template.RenderString("format/commit_footer.tmpl", map[string]any{
+		"CommentChar": s.commentChar,
+		"Cleanup":     s.cleanup,
+		"Verbose":     s.verbose > 0,
+		"Scissors":    scissors,
+		"GitStatus":   strings.TrimRight(status, "\n"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(message + "\n\n")
+	b.WriteString(templateComments(tmpl, s.commentChar))
+	if err := s.comment(&b, footer); err != nil {
+		return "", err
+	}
+	if s.verbose < 1 {
+		return b.String(), nil
+	}
+
+	if s.verbose > 1 {
+		fmt.Fprintf(&b, "%s\n%s Changes to be committed:\n", s.commentChar, s.commentChar)
+	}
+	b.WriteString(staged)
+	if s.verbose > 1 && unstaged != "" {
+		fmt.Fprintf(&b, "%s %s\n%s Changes not staged for commit:\n",
+			s.commentChar, strings.Repeat("-", 50), s.commentChar)
+		b.WriteString(unstaged)
+	}
+	return b.String(), nil
+}
+
+// truncate removes the scissors line and everything below it from the edited
+// message, as Git does for `git commit -v`.
+func (s *editSettings) truncate(text string) string {
+	if strings.HasPrefix(text, s.cutLine()) {
+		return ""
+	}
+	if i := strings.Index(text, "\n"+s.cutLine()); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// commitArgs returns the arguments that commit the edited message in file,
+// cleaning it up the way `git commit` would have.
+func (s *editSettings) commitArgs(file string) []string {
+	cleanup := s.cleanup
+	if cleanup == cleanupScissors {
+		// The message has already been cut at the scissors.
+		cleanup = cleanupWhitespace
+	}
+	return []string{
+		"-c", "core.commentChar=" + s.commentChar,
+		"commit", "--cleanup=" + cleanup, "--file", file,
+	}
+}
+
+// commitWithEditor opens the user's editor with the generated message, a
+// commented status footer, and the staged diff, laid out as `git commit -v`
+// would, then commits with whatever the user saved.
+func commitWithEditor(config *Config, message, staged string) error {
+	tempDir, err := os.MkdirTemp("", "git-auto-commit-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	settings, err := readEditSettings(message)
+	if err != nil {
+		log.Errorw("failed to read commit settings",
+			"error", err)
+		return err
+	}
+
+	gitStatus, err := git.Status()
+	if err != nil {
+		return err
+	}
+	var unstaged string
+	if settings.verbose > 1 {
+		if unstaged, err = git.Diff(false); err != nil {
+			return err
+		}
+	}
+
+	text, err := settings.editMessage(message, commitTemplate(config), staged, gitStatus, unstaged)
+	if err != nil {
+		return err
+	}
+
+	// Write message to a temporary file
+	path := filepath.Join(tempDir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return err
+	}
+
+	// Open an editor to allow edits to the generated message.
+	if err := runEditor(path); err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(settings.truncate(string(edited))), 0o644); err != nil {
+		return err
+	}
+
+	args := append(settings.commitArgs(path), commitArgs(config)...)
+
+	// Open an editor to confirm the commit with the generated message.
+	cmd := exec.Command("git", args...)
+	cmd.SetStdin(os.Stdin)
+	cmd.SetStdout(os.Stdout)
+	cmd.SetStderr(os.Stderr)
+	return cmd.Run()
+}
+
+// findEditor returns the editor Git would use for a commit message, as a
+// shell command.
+func findEditor() (string, error) {
+	editor, err := git.Editor()
+	if err != nil || editor == "" {
+		log.Warnw("no editor found",
+			"error", err)
+		return "", errors.New("no editor found, set $GIT_EDITOR, core.editor, $VISUAL, or $EDITOR")
+	}
+	return editor, nil
+}
+
+// runEditor opens the file at path in the user's editor and waits for it to
+// exit. Like Git, the editor is run by the shell, so it may have arguments
+// and quoted paths, and an editor of ":" isn't run at all.
+func runEditor(path string) error {
+	editor, err := findEditor()
+	if err != nil {
+		return err
+	}
+	if editor == ":" {
+		return nil
+	}
+
+	log.Infow("opening editor for review",
+		"editor", editor,
+		"path", path)
+
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	cmd.SetStdin(os.Stdin)
+	cmd.SetStdout(os.Stdout)
+	cmd.SetStderr(os.Stderr)
+	return cmd.Run()
+}
@@ -0,0 +1,127 @@
+package git_auto_commit
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/util/exec"
+)
+
+var _ = Describe("the commit message editor", func() {
+	const (
+		status = "On branch main\nChanges to be committed:\n\tnew file:   b\n\n"
+		staged = "diff --git a/b b/b\n+new\n"
+	)
+
+	It("lays the message out as git commit -v does", func() {
+		settings := &editSettings{commentChar: ";", cleanup: cleanupStrip, verbose: 1}
+
+		text, err := settings.editMessage("Add b", "", staged, status, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal("Add b\n\n" +
+			"; Please enter the commit message for your changes. Lines starting\n" +
+			"; with ';' will be ignored, and an empty message aborts the commit.\n" +
+			";\n" +
+			"; On branch main\n" +
+			"; Changes to be committed:\n" +
+			";\tnew file:   b\n" +
+			";\n" +
+			"; ------------------------ >8 ------------------------\n" +
+			"; Do not modify or remove the line above.\n" +
+			"; Everything below it will be ignored.\n" +
+			staged))
+
+		Expect(settings.truncate(text + "more\n")).To(HavePrefix("Add b\n\n; Please enter"))
+		Expect(settings.truncate(text)).NotTo(ContainSubstring("diff --git"))
+		Expect(settings.commitArgs("MSG")).To(Equal([]string{
+			"-c", "core.commentChar=;", "commit", "--cleanup=strip", "--file", "MSG",
+		}))
+	})
+
+	It("starts with the scissors when cleaning up with them", func() {
+		settings := &editSettings{commentChar: "#", cleanup: cleanupScissors, verbose: 1}
+
+		text, err := settings.editMessage("Add b", "", staged, status, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(HavePrefix("Add b\n\n" +
+			"# ------------------------ >8 ------------------------\n" +
+			"# Do not modify or remove the line above.\n" +
+			"# Everything below it will be ignored.\n" +
+			"#\n" +
+			"# On branch main\n"))
+		Expect(settings.truncate(text)).To(Equal("Add b\n\n"))
+		Expect(settings.commitArgs("MSG")).To(ContainElement("--cleanup=whitespace"))
+	})
+
+	It("keeps comments and leaves out the diff when asked to", func() {
+		settings := &editSettings{commentChar: "#", cleanup: cleanupWhitespace, verbose: 0}
+
+		text, err := settings.editMessage("Add b", "", staged, status, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal("Add b\n\n" +
+			"# Please enter the commit message for your changes. Lines starting\n" +
+			"# with '#' will be kept; you may remove them yourself if you want to.\n" +
+			"# An empty message aborts the commit.\n" +
+			"#\n" +
+			"# On branch main\n" +
+			"# Changes to be committed:\n" +
+			"#\tnew file:   b\n" +
+			"#\n"))
+	})
+
+	It("shows unstaged changes when very verbose", func() {
+		settings := &editSettings{commentChar: "#", cleanup: cleanupStrip, verbose: 2}
+
+		text, err := settings.editMessage("Add b", "", staged, status, "diff --git a/c b/c\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(HaveSuffix("# Everything below it will be ignored.\n" +
+			"#\n" +
+			"# Changes to be committed:\n" +
+			staged +
+			"# --------------------------------------------------\n" +
+			"# Changes not staged for commit:\n" +
+			"diff --git a/c b/c\n"))
+	})
+
+	It("keeps the comments from the team's template", func() {
+		settings := &editSettings{commentChar: "#", cleanup: cleanupStrip, verbose: 0}
+		tmpl := "Summary\n\n# Why is this change needed?\n# Refs: ABC-123\n"
+
+		text, err := settings.editMessage("Add b", tmpl, staged, status, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(HavePrefix("Add b\n\n" +
+			"# Why is this change needed?\n" +
+			"# Refs: ABC-123\n" +
+			"\n" +
+			"# Please enter the commit message"))
+	})
+
+	It("runs the editor Git would, through the shell", func() {
+		var args [][]string
+		original := exec.GetCommand()
+		DeferCleanup(exec.SetCommand, original)
+		exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+			args = append(args, append([]string{name}, arg...))
+			if name == "git" {
+				return exec.NewMockCmd([]byte("'/opt/My Editor/edit' --wait\n"), nil)
+			}
+			return exec.NewMockCmd(nil, nil)
+		})
+
+		Expect(runEditor("COMMIT_EDITMSG")).To(Succeed())
+		Expect(args).To(Equal([][]string{
+			{"git", "var", "GIT_EDITOR"},
+			{"sh", "-c", `'/opt/My Editor/edit' --wait "$@"`, "'/opt/My Editor/edit' --wait", "COMMIT_EDITMSG"},
+		}))
+	})
+
+	DescribeTable("picks an unused comment character for core.commentChar=auto",
+		func(message, expected string) {
+			char, err := pickCommentChar(message)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(char).To(Equal(expected))
+		},
+		Entry("the default", "Add b\n\nSome details.", "#"),
+		Entry("Markdown headings", "Add b\n\n# Why\n\n; and more", "@"),
+	)
+})
@@ -0,0 +1,60 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// ignoreFile is the name of the file, at the top of the repository, that
+// lists gitignore-style patterns of paths to leave out of prompts.
+const ignoreFile = ".autocommitignore"
+
+// excludePathspecs returns pathspecs for the paths whose contents must not be
+// sent to the provider, from config.Exclude and the repository's ignore file.
+// The include pathspecs match the excluded paths and the exclude pathspecs
+// match everything else; both are empty when nothing is excluded.
+func excludePathspecs(config *Config) (include, exclude []string) {
+	patterns := append([]string{}, config.Exclude...)
+
+	if top, err := git.TopLevel(); err == nil && top != "" {
+		fromFile, err := readIgnoreFile(filepath.Join(top, ignoreFile))
+		if err != nil {
+			log.Warnw("failed to read ignore file",
+				"path", filepath.Join(top, ignoreFile),
+				"error", err)
+		}
+		patterns = append(patterns, fromFile...)
+	}
+
+	return git.ExcludePathspecs(patterns)
+}
+
+// readIgnoreFile returns the patterns in a gitignore-style file, skipping
+// blank lines and comments. A missing file has no patterns.
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,160 @@
+package git_auto_commit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/message"
+	"github.com/ivy/git-auto-commit/template"
+	"github.com/ivy/git-auto-commit/util/diff"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// Commit message formats for config.Format.
+const (
+	// formatClassic is the free-form summary and body described by Tim Pope.
+	formatClassic = "classic"
+	// formatConventional is the Conventional Commits format.
+	formatConventional = "conventional"
+)
+
+// maxRevisions is how many times the model is asked to correct a message
+// that doesn't follow the format before giving up.
+const maxRevisions = 2
+
+// commitFormat renders the description of the configured message format for
+// a prompt. The staged diff, if it can be parsed, is used to suggest a type
+// and scope for Conventional Commits.
+func commitFormat(config *Config, staged string) (string, error) {
+	var (
+		name string
+		data any
+	)
+	switch config.Format {
+	case formatClassic, "":
+		name = "format/commit.tmpl"
+	case formatConventional:
+		name = "format/conventional.tmpl"
+		// Summarized diffs can't be parsed, and only get the generic format.
+		hint := &message.Hint{}
+		if files, err := diff.Parse(staged); err == nil {
+			hint = message.Infer(files)
+		}
+		data = hint
+	default:
+		return "", fmt.Errorf("unknown commit message format %q", config.Format)
+	}
+
+	format, err := template.RenderString(name, data)
+	if err != nil {
+		log.Errorw("failed to render commit message format",
+			"format", config.Format,
+			"error", err)
+		return "", err
+	}
+	return format, nil
+}
+
+// Default subject lengths for each format, when config.SubjectLength isn't
+// set.
+const (
+	classicSubjectLength      = 50
+	conventionalSubjectLength = 72
+)
+
+// lintMessage repairs what the configured lint rules can fix in a generated
+// message, and returns it with the problems that remain, including any with
+// the configured format.
+func lintMessage(config *Config, msg string) (string, []message.Violation, error) {
+	subjectLength := config.SubjectLength
+	if subjectLength == 0 {
+		subjectLength = classicSubjectLength
+		if config.Format == formatConventional {
+			subjectLength = conventionalSubjectLength
+		}
+	}
+
+	// A history that isn't in the imperative mood would have every message
+	// sent back for revision, against the style the model was asked to follow.
+	ignore := config.LintIgnore
+	if style := repoStyle(config); style != nil && style.NotImperative {
+		ignore = append(slices.Clip(ignore), message.RuleImperative)
+	}
+
+	linter, err := message.NewLinter(subjectLength, config.BodyWidth, ignore)
+	if err != nil {
+		return "", nil, err
+	}
+
+	msg = linter.Fix(msg)
+	problems := linter.Lint(msg)
+
+	if config.Format == formatConventional {
+		if _, err := message.ParseConventional(msg); err != nil {
+			problems = append(problems, message.Violation{
+				Rule:    formatConventional,
+				Message: err.Error(),
+			})
+		}
+	}
+	return msg, problems, nil
+}
+
+// reviseMessage lints a message generated from prompt and, while problems
+// remain that can't be fixed automatically, asks the model to correct them.
+// After maxRevisions attempts, a message that still breaks the format is an
+// error, while one that only breaks lint rules is used with a warning.
+func reviseMessage(ctx context.Context, config *Config, prompt, msg string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		fixed, problems, err := lintMessage(config, msg)
+		if err != nil {
+			return "", err
+		}
+		if len(problems) == 0 {
+			return fixed, nil
+		}
+
+		if attempt == maxRevisions {
+			if slices.ContainsFunc(problems, func(v message.Violation) bool {
+				return v.Rule == formatConventional
+			}) {
+				return "", fmt.Errorf("generated message is invalid after %d revisions: %s",
+					maxRevisions, joinViolations(problems))
+			}
+			log.Warnw("generated message still has lint problems",
+				"problems", joinViolations(problems))
+			return fixed, nil
+		}
+
+		log.Infow("generated message has problems, asking for a revision",
+			"attempt", attempt+1,
+			"problems", joinViolations(problems))
+
+		revision, err := template.RenderString("prompt/revise.tmpl", map[string]any{
+			"Prompt":   prompt,
+			"Response": fixed,
+			"Problems": problems,
+		})
+		if err != nil {
+			log.Errorw("failed to execute revise template",
+				"error", err)
+			return "", err
+		}
+
+		msg, err = generate(ctx, config, "Revising commit message", revision)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// joinViolations describes violations on one line.
+func joinViolations(violations []message.Violation) string {
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.String()
+	}
+	return strings.Join(descriptions, "; ")
+}
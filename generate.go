@@ -0,0 +1,179 @@
+package git_auto_commit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ivy/git-auto-commit/provider"
+	"github.com/ivy/git-auto-commit/util/log"
+	"github.com/ivy/git-auto-commit/util/term"
+)
+
+// generate sends the prompt to the provider selected by the Config and returns
+// the generated text. The label describes what's being generated, e.g.
+// "Generating commit message".
+//
+// When stderr is a terminal, a spinner showing the label is drawn until the
+// first token arrives, after which tokens are rendered live as they stream in.
+// Otherwise, nothing is written and tokens are only logged at debug level.
+func generate(ctx context.Context, cfg *Config, label, prompt string) (string, error) {
+	completer, err := provider.New(cfg.Config)
+	if err != nil {
+		log.Errorw("failed to create provider",
+			"provider", cfg.Provider,
+			"error", err)
+		return "", err
+	}
+	completer = cfg.usage.track(completer)
+
+	req := &provider.Request{
+		Model:  cfg.Model,
+		Prompt: prompt,
+	}
+
+	if term.IsTerminal(os.Stderr) {
+		return generateLive(ctx, completer, req, os.Stderr, label)
+	}
+
+	resp, err := completer.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// generateQuietly is like generate, but only draws the spinner while waiting.
+// It's used for structured responses, such as JSON, that aren't meant to be
+// read as they stream in.
+func generateQuietly(ctx context.Context, cfg *Config, label, prompt string) (string, error) {
+	completer, err := provider.New(cfg.Config)
+	if err != nil {
+		log.Errorw("failed to create provider",
+			"provider", cfg.Provider,
+			"error", err)
+		return "", err
+	}
+	completer = cfg.usage.track(completer)
+
+	if term.IsTerminal(os.Stderr) {
+		spinner := term.NewSpinner(os.Stderr, label+"...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	resp, err := completer.Complete(ctx, &provider.Request{
+		Model:  cfg.Model,
+		Prompt: prompt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// generateLive completes the request while drawing a spinner and then the
+// streamed tokens to w.
+func generateLive(
+	ctx context.Context,
+	completer provider.Completer,
+	req *provider.Request,
+	w io.Writer,
+	label string,
+) (string, error) {
+	spinner := term.NewSpinner(w, label+"...")
+	spinner.Start()
+
+	streamed := false
+	req.OnToken = func(token string) {
+		if !streamed {
+			spinner.Stop()
+			streamed = true
+		}
+		fmt.Fprint(w, token)
+	}
+
+	resp, err := completer.Complete(ctx, req)
+	spinner.Stop()
+	if streamed {
+		// Separate the streamed text from whatever is printed next.
+		fmt.Fprint(w, "\n\n")
+	}
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// generateCandidates sends the prompt to the configured provider n times in
+// parallel, spreading the sampling temperature across requests so that the
+// responses differ, and returns the responses in request order. When stderr
+// is a terminal, a spinner showing the label is drawn until all responses
+// arrive.
+func generateCandidates(
+	ctx context.Context, cfg *Config, label, prompt string, n int,
+) ([]string, error) {
+	completer, err := provider.New(cfg.Config)
+	if err != nil {
+		log.Errorw("failed to create provider",
+			"provider", cfg.Provider,
+			"error", err)
+		return nil, err
+	}
+	completer = cfg.usage.track(completer)
+
+	if term.IsTerminal(os.Stderr) {
+		spinner := term.NewSpinner(os.Stderr, label+"...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		results  = make([]string, n)
+	)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			temperature := candidateTemperature(i, n)
+			resp, err := completer.Complete(ctx, &provider.Request{
+				Model:       cfg.Model,
+				Prompt:      prompt,
+				Temperature: &temperature,
+			})
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = resp.Content
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// candidateTemperature spreads n temperatures evenly between 0.2 and 1.0, so
+// the first candidate is the most conservative.
+func candidateTemperature(i, n int) float64 {
+	const low, high = 0.2, 1.0
+	if n <= 1 {
+		return low
+	}
+	return low + (high-low)*float64(i)/float64(n-1)
+}
@@ -0,0 +1,64 @@
+package git_auto_commit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+// streamingCompleter is a Completer that streams a fixed list of tokens.
+type streamingCompleter struct {
+	tokens []string
+	err    error
+}
+
+func (s *streamingCompleter) Complete(_ context.Context, req *provider.Request) (*provider.Response, error) {
+	var content string
+	for _, token := range s.tokens {
+		req.OnToken(token)
+		content += token
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &provider.Response{Content: content}, nil
+}
+
+var _ = Describe("generateLive", func() {
+	It("renders streamed tokens after clearing the spinner", func() {
+		out := &bytes.Buffer{}
+		completer := &streamingCompleter{tokens: []string{"Fix ", "typo"}}
+
+		content, err := generateLive(
+			context.Background(), completer, &provider.Request{}, out, "Generating",
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(Equal("Fix typo"))
+		Expect(out.String()).To(HaveSuffix("\r\033[KFix typo\n\n"))
+	})
+
+	It("clears the spinner when the provider fails before streaming", func() {
+		out := &bytes.Buffer{}
+		completer := &streamingCompleter{err: errors.New("boom")}
+
+		_, err := generateLive(
+			context.Background(), completer, &provider.Request{}, out, "Generating",
+		)
+		Expect(err).To(MatchError("boom"))
+		Expect(out.String()).To(HaveSuffix("\r\033[K"))
+	})
+})
+
+var _ = Describe("candidateTemperature", func() {
+	It("spreads temperatures from conservative to creative", func() {
+		Expect(candidateTemperature(0, 1)).To(BeNumerically("~", 0.2))
+		Expect(candidateTemperature(0, 3)).To(BeNumerically("~", 0.2))
+		Expect(candidateTemperature(1, 3)).To(BeNumerically("~", 0.6))
+		Expect(candidateTemperature(2, 3)).To(BeNumerically("~", 1.0))
+	})
+})
@@ -2,29 +2,22 @@ package git_auto_commit
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	stdexec "os/exec"
-	"path/filepath"
+	"slices"
 	"strings"
-
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"time"
 
 	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/message"
 	"github.com/ivy/git-auto-commit/template"
 	"github.com/ivy/git-auto-commit/util/exec"
 	"github.com/ivy/git-auto-commit/util/git"
 	"github.com/ivy/git-auto-commit/util/log"
-)
-
-const (
-	scissors    = "------------------------ >8 ------------------------"
-	commentChar = "#"
+	"github.com/ivy/git-auto-commit/util/term"
 )
 
 // Command is the configuration for the git-auto-commit and
@@ -32,124 +25,221 @@ const (
 type Config struct {
 	*config.Config
 
-	// Verbose opens an editor for the user to review messages.
+	// Verbose opens an editor for the user to review messages instead of the
+	// interactive review prompt.
 	Verbose bool
 
-	// Yes skips the editor and directly commits the message.
+	// Yes skips the review prompt and the editor, and directly commits the
+	// message.
 	Yes bool
 
 	// Message provides additional context for the commit message. It's supplied
 	// by the user on the command line.
 	Message string
 
+	// Amend regenerates the message for HEAD from all of its changes, including
+	// any that are staged, and amends it instead of creating a new commit.
+	Amend bool
+
+	// Split divides the staged changes into several focused commits instead
+	// of committing them all at once.
+	Split bool
+
+	// Candidates is the number of alternative commit messages to generate and
+	// pick from. Values less than 2 generate a single message, and values over
+	// MaxCandidates are an error.
+	Candidates int
+
+	// DryRun writes the generated message to Output instead of committing.
+	DryRun bool
+
+	// Output is the file a dry run writes to. It defaults to stdout, as does
+	// "-".
+	Output string
+
+	// JSON makes a dry run write the message as JSON, along with the model,
+	// token usage, and timings.
+	JSON bool
+
+	// FromDiff is a file, or "-" for stdin, holding a unified diff to describe
+	// instead of the staged changes. It implies DryRun.
+	FromDiff string
+
+	// FromRange is a revision range, such as "main...HEAD", whose diff is
+	// described instead of the staged changes. It implies DryRun.
+	FromRange string
+
 	// ExtraArgs are additional arguments to pass to the used git/gh command.
 	ExtraArgs []string
-}
 
-var editorFallbacks = []string{"nano", "vim", "vi"}
-
-// prefixLines prefixes each line of the input reader with the given prefix
-// string and writes the result to the output writer.  It returns an error if
-// one occurs during reading or writing.
-func prefixLines(r io.Reader, w io.Writer, prefix string) error {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		prefixedLine := prefix + line + "\n" // Add newline back
-		_, err := w.Write([]byte(prefixedLine))
-		if err != nil {
-			return err
-		}
-	}
-	return scanner.Err()
+	// previous is the message of the commit being amended, if any.
+	previous string
+
+	// excluded lists the changed files whose contents were left out of the
+	// diff, so that the prompt can still mention them.
+	excluded []string
+
+	// style is the learned style of the repository's commit messages, once
+	// it's been sampled.
+	style *message.Style
+
+	// template is the team's commit message template, from commit.template,
+	// once it's been read.
+	template *string
+
+	// usage tallies the requests made to the provider.
+	usage usageTally
 }
 
 // GenerateCommitMessage generates a commit message for the given staged changes
 // and Config using AI.
 func GenerateCommitMessage(ctx context.Context, config *Config, staged string) (string, error) {
-	log.Debugw("generating commit message",
-		"model", config.Model,
-		"message_context", config.Message)
-
-	client := openai.NewClient(
-		option.WithAPIKey(config.OpenAIAPIKey),
-	)
-
-	format, err := template.RenderString("format/commit.tmpl", nil)
+	prompt, err := commitPrompt(config, staged)
 	if err != nil {
-		log.Errorw("failed to render commit message format",
-			"error", err)
 		return "", err
 	}
-
-	prompt, err := template.RenderString("prompt/commit.tmpl", map[string]any{
-		"Staged":  staged,
-		"Format":  format,
-		"Message": config.Message,
-	})
+	message, err := generate(ctx, config, "Generating commit message", prompt)
 	if err != nil {
-		log.Errorw("failed to execute commit message template",
-			"error", err)
 		return "", err
 	}
-	log.Debugw("commit message template executed", "prompt", prompt)
+	return reviseMessage(ctx, config, prompt, message)
+}
 
-	stream := client.Chat.Completions.NewStreaming(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			}),
-			Seed:  openai.Int(0),
-			Model: openai.F(openai.ChatModel(config.Model)),
-		},
-	)
+// MaxCandidates is the most alternative commit messages that can be generated
+// at once. Each one is a separate request, and they're all sent in parallel.
+const MaxCandidates = 10
 
-	acc := openai.ChatCompletionAccumulator{}
+// GenerateCommitMessages generates n alternative commit messages for the given
+// staged changes and Config using AI. It returns an error if n is more than
+// MaxCandidates.
+func GenerateCommitMessages(ctx context.Context, config *Config, staged string, n int) ([]string, error) {
+	if n > MaxCandidates {
+		return nil, fmt.Errorf("can't generate more than %d candidates at once, not %d", MaxCandidates, n)
+	}
+	prompt, err := commitPrompt(config, staged)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := generateCandidates(ctx, config,
+		fmt.Sprintf("Generating %d commit messages", n), prompt, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, candidate := range candidates {
+		if candidates[i], err = reviseMessage(ctx, config, prompt, candidate); err != nil {
+			return nil, err
+		}
+	}
+	return candidates, nil
+}
 
-	for stream.Next() {
-		chunk := stream.Current()
-		acc.AddChunk(chunk)
-		log.Debugw("stream chunk received", "chunk", chunk)
+// commitPrompt renders the prompt used to generate commit messages.
+func commitPrompt(config *Config, staged string) (string, error) {
+	return renderCommitPrompt(config, staged, config.excluded)
+}
 
-		// if content, ok := acc.JustFinishedContent(); ok {
-		// 	return content, nil
-		// }
+// renderCommitPrompt renders the commit prompt for staged, mentioning the
+// excluded paths by name.
+func renderCommitPrompt(config *Config, staged string, excluded []string) (string, error) {
+	log.Debugw("generating commit message",
+		"provider", config.Provider,
+		"model", config.Model,
+		"message_context", config.Message)
 
-		if refusal, ok := acc.JustFinishedRefusal(); ok {
-			log.Warnw("AI refused to generate commit message",
-				"refusal", refusal)
-			return "", fmt.Errorf("refusal: %s", refusal)
-		}
+	format, err := commitFormat(config, staged)
+	if err != nil {
+		return "", err
 	}
 
-	if err := stream.Err(); err != nil {
-		log.Errorw("stream error while generating commit message",
+	prompt, err := template.RenderString("prompt/commit.tmpl", map[string]any{
+		"Staged":   staged,
+		"Format":   format,
+		"Message":  config.Message,
+		"Previous": config.previous,
+		"Excluded": excluded,
+		"Style":    repoStyle(config),
+		"Template": commitTemplate(config),
+	})
+	if err != nil {
+		log.Errorw("failed to execute commit message template",
 			"error", err)
 		return "", err
 	}
+	log.Debugw("commit message template executed", "prompt", prompt)
 
-	return acc.Choices[0].Message.Content, nil
+	return prompt, nil
 }
 
 // AutoCommit uses Git to commit staged changes, generating a commit message
 // using AI.
 func AutoCommit(ctx context.Context, config *Config) error {
+	start := time.Now()
 	log.Infow("starting auto-commit process",
 		"verbose", config.Verbose,
+		"amend", config.Amend,
+		"dry_run", config.DryRun,
 		"extra_args", config.ExtraArgs)
 
-	// 1. Get the staged changes.
-	// TODO(ivy): handle amending commits
-	staged, err := git.Diff(true)
+	// Treat `-- --amend` the same as --amend.
+	if slices.Contains(config.ExtraArgs, "--amend") {
+		config.Amend = true
+	}
+
+	// Changes that aren't staged can't be committed, only described.
+	fromInput := config.FromDiff != "" || config.FromRange != ""
+	if fromInput {
+		if config.Amend {
+			return errors.New("--amend can't be combined with --from-diff or --from-range")
+		}
+		config.DryRun = true
+	}
+
+	if config.Split {
+		if config.DryRun {
+			return errors.New("--split can't be combined with a dry run")
+		}
+		return Split(ctx, config)
+	}
+
+	// 1. Get the staged changes, or the diff to describe instead.
+	var (
+		staged string
+		err    error
+	)
+	if fromInput {
+		staged, err = inputDiff(config)
+	} else {
+		staged, err = stagedChanges(config)
+	}
 	if err != nil {
 		log.Errorw("failed to get staged changes",
 			"error", err)
 		return err
 	}
 
+	// Mask secrets and shrink the diff to fit the model, keeping the full
+	// diff for the editor.
+	prompted := staged
+	if err := redactSecrets(config, &prompted); err != nil {
+		return err
+	}
+	prompted, err = fitDiff(ctx, config, prompted)
+	if err != nil {
+		log.Errorw("failed to fit staged changes to the token budget",
+			"error", err)
+		return err
+	}
+
+	if config.DryRun {
+		return dryRun(ctx, config, prompted, start)
+	}
+
+	// Review prompts need a terminal to read answers from.
+	in := bufio.NewReader(os.Stdin)
+	interactive := !config.Yes && term.IsTerminal(os.Stdin)
+
 	// 2. Generate a commit message.
-	message, err := GenerateCommitMessage(ctx, config, string(staged))
+	message, err := nextCommitMessage(ctx, config, prompted, in, interactive)
 	if err != nil {
 		log.Errorw("failed to generate commit message",
 			"error", err)
@@ -159,101 +249,190 @@ func AutoCommit(ctx context.Context, config *Config) error {
 	log.Debugw("generated commit message",
 		"message", message)
 
-	// 3. Optionally, open the editor for the user to review the message.
-	if config.Verbose {
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			// If no editor is set, use the first installed fallback.
-			for _, fallback := range editorFallbacks {
-				if _, err := stdexec.LookPath(fallback); err == nil {
-					editor = fallback
-					break
-				}
-			}
+	// 3. Let the user review the message, unless they asked not to.
+	switch {
+	case config.Yes:
+		return commit(config, message)
+	case config.Verbose:
+		return commitWithEditor(config, message, staged)
+	case !interactive:
+		log.Infow("stdin is not a terminal, committing without review")
+		return commit(config, message)
+	}
+
+	for {
+		choice, err := reviewMessage(in, os.Stderr, message)
+		if err != nil {
+			return err
 		}
-		if editor == "" {
-			log.Warnw("no editor found",
-				"fallbacks_tried", editorFallbacks)
-			return errors.New("no editor found, set $EDITOR")
+
+		switch choice {
+		case choiceAccept:
+			return commit(config, message)
+		case choiceEdit:
+			return commitWithEditor(config, message, staged)
+		case choiceQuit:
+			return ErrAborted
+		case choiceRegenerate:
+			guidance, err := term.Prompt(in, os.Stderr, "Additional guidance (optional): ")
+			if err != nil {
+				return err
+			}
+			if guidance != "" {
+				config.Message = strings.TrimSpace(config.Message + "\n" + guidance)
+			}
+
+			message, err = nextCommitMessage(ctx, config, prompted, in, interactive)
+			if err != nil {
+				log.Errorw("failed to regenerate commit message",
+					"error", err)
+				return err
+			}
+			log.Debugw("regenerated commit message",
+				"message", message)
 		}
+	}
+}
 
-		log.Infow("opening editor for commit message review",
-			"editor", editor)
+// stagedChanges returns the diff that the commit message should describe. When
+// amending, that's everything between HEAD's parent and the index, and the
+// existing HEAD message is recorded as context for the prompt. Excluded paths
+// are left out of the diff and recorded by name.
+func stagedChanges(config *Config) (string, error) {
+	include, exclude := excludePathspecs(config)
 
-		tempDir, err := os.MkdirTemp("", "git-auto-commit-*")
+	var base string
+	if config.Amend {
+		previous, err := git.Message("HEAD")
 		if err != nil {
-			return err
+			return "", fmt.Errorf("failed to read HEAD's message: %w", err)
 		}
-		defer os.RemoveAll(tempDir)
+		config.previous = previous
 
-		// Write message to a temporary file
-		f, err := os.Create(filepath.Join(tempDir, "COMMIT_EDITMSG"))
+		base, err = git.ParentOrEmptyTree("HEAD")
 		if err != nil {
-			return err
+			return "", err
 		}
-		defer f.Close()
-		defer os.Remove(f.Name())
+	}
 
-		gitStatus, err := git.Status()
+	if len(include) > 0 {
+		excluded, err := git.StagedFiles(base, include...)
 		if err != nil {
-			return err
+			return "", fmt.Errorf("failed to list excluded files: %w", err)
 		}
+		config.excluded = excluded
+	}
+
+	if base == "" {
+		return git.Diff(true, exclude...)
+	}
+	return git.DiffCachedAgainst(base, exclude...)
+}
 
-		footer, err := template.RenderString("format/commit_footer.tmpl", map[string]any{
-			// TODO(ivy): use `git core.commentChar`
-			"CommentChar": commentChar,
-			"Scissors":    scissors,
-			"GitStatus":   gitStatus,
-		})
+// inputDiff returns the diff to describe when it doesn't come from the index:
+// a unified diff read from config.FromDiff, or the diff of config.FromRange
+// with excluded paths left out and recorded by name. Mail headers before the
+// first "diff --git" line, as in `git format-patch` output, are dropped.
+func inputDiff(config *Config) (string, error) {
+	var (
+		text   string
+		source string
+	)
+	switch {
+	case config.FromDiff == "-":
+		source = "stdin"
+		b, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return err
+			return "", err
 		}
-
-		if _, err = f.WriteString(message + "\n\n"); err != nil {
-			return err
+		text = string(b)
+	case config.FromDiff != "":
+		source = config.FromDiff
+		b, err := os.ReadFile(config.FromDiff)
+		if err != nil {
+			return "", err
 		}
-
-		r := bytes.NewBufferString(footer)
-		w := new(bytes.Buffer)
-		if err = prefixLines(r, w, commentChar+" "); err != nil {
-			return err
+		text = string(b)
+	default:
+		source = config.FromRange
+		for _, rev := range strings.Fields(config.FromRange) {
+			if strings.HasPrefix(rev, "-") {
+				return "", fmt.Errorf("invalid revision range %q", config.FromRange)
+			}
 		}
-		if _, err = f.WriteString(w.String()); err != nil {
-			return err
+
+		include, exclude := excludePathspecs(config)
+		if len(include) > 0 {
+			excluded, err := git.RevisionFiles(config.FromRange, include...)
+			if err != nil {
+				return "", fmt.Errorf("failed to list excluded files: %w", err)
+			}
+			config.excluded = excluded
 		}
 
-		if _, err = f.WriteString(staged); err != nil {
-			return err
+		var err error
+		if text, err = git.DiffRevisions(config.FromRange, exclude...); err != nil {
+			return "", fmt.Errorf("failed to diff %s: %w", config.FromRange, err)
 		}
+	}
 
-		// Open an editor to allow edits to the generated message.
-		editorParts := append(strings.Split(editor, " "), f.Name())
-		cmd := exec.Command(editorParts[0], editorParts[1:]...)
-		cmd.SetStdin(os.Stdin)
-		cmd.SetStdout(os.Stdout)
-		cmd.SetStderr(os.Stderr)
-		if err := cmd.Run(); err != nil {
-			return err
+	if !strings.HasPrefix(text, "diff --git ") {
+		if i := strings.Index(text, "\ndiff --git "); i >= 0 {
+			text = text[i+1:]
 		}
+	}
+	if strings.TrimSpace(text) == "" && len(config.excluded) == 0 {
+		return "", fmt.Errorf("there are no changes in %s", source)
+	}
+	return text, nil
+}
 
-		args := []string{"commit", "--file", f.Name()}
-		args = append(args, config.ExtraArgs...)
+// commitArgs returns the arguments to pass to `git commit` after the message
+// options.
+func commitArgs(config *Config) []string {
+	if config.Amend && !slices.Contains(config.ExtraArgs, "--amend") {
+		return append([]string{"--amend"}, config.ExtraArgs...)
+	}
+	return config.ExtraArgs
+}
 
-		// Open an editor to confirm the commit with the generated message.
-		cmd = exec.Command("git", args...)
-		cmd.SetStdin(os.Stdin)
-		cmd.SetStdout(os.Stdout)
-		cmd.SetStderr(os.Stderr)
-		return cmd.Run()
+// nextCommitMessage generates a commit message for the staged changes. When
+// config.Candidates asks for alternatives, the user picks one of them; if
+// there's no terminal to ask, the first candidate is used.
+func nextCommitMessage(
+	ctx context.Context,
+	config *Config,
+	staged string,
+	in *bufio.Reader,
+	interactive bool,
+) (string, error) {
+	if config.Candidates < 2 {
+		return GenerateCommitMessage(ctx, config, staged)
 	}
 
+	candidates, err := GenerateCommitMessages(ctx, config, staged, config.Candidates)
+	if err != nil {
+		return "", err
+	}
+	if !interactive {
+		return candidates[0], nil
+	}
+
+	i, err := pickCandidate(in, os.Stderr, candidates)
+	if err != nil {
+		return "", err
+	}
+	return candidates[i], nil
+}
+
+// commit runs `git commit` with the given message and any extra args.
+func commit(config *Config, message string) error {
 	log.Infow("committing changes",
 		"extra_args", config.ExtraArgs)
 
-	// 3. Otherwise, commit the changes and pass any extra args.
 	cmd := exec.Command(
 		"git",
-		append([]string{"commit", "--file", "-"}, config.ExtraArgs...)...,
+		append([]string{"commit", "--file", "-"}, commitArgs(config)...)...,
 	)
 	cmd.SetStdin(strings.NewReader(message))
 	cmd.SetStdout(os.Stdout)
@@ -0,0 +1,1087 @@
+package git_auto_commit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	git_auto_commit "github.com/ivy/git-auto-commit"
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+	"github.com/ivy/git-auto-commit/util/exec"
+)
+
+func TestGitAutoCommit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "git-auto-commit Suite")
+}
+
+// fakeCompleter is a Completer that returns canned responses and records the
+// requests it receives.
+type fakeCompleter struct {
+	mu        sync.Mutex
+	requests  []*provider.Request
+	responses []string
+	usage     provider.Usage
+	err       error
+}
+
+func (f *fakeCompleter) Complete(_ context.Context, req *provider.Request) (*provider.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	content := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	return &provider.Response{Content: content, Usage: f.usage}, nil
+}
+
+// registerFake registers fake under the "fake" provider name and returns a
+// Config that selects it.
+func registerFake(fake *fakeCompleter) *git_auto_commit.Config {
+	provider.Register("fake", func(*config.Config) (provider.Completer, error) {
+		return fake, nil
+	})
+	DeferCleanup(provider.Unregister, "fake")
+
+	return &git_auto_commit.Config{
+		Config: &config.Config{
+			Provider: "fake",
+			Model:    "fake-model",
+		},
+	}
+}
+
+// fakeGit replaces exec.Command for the duration of a spec. Commands are
+// matched by their space-joined arguments; unmatched commands succeed with no
+// output. It returns a pointer to the list of mock commands that were run.
+func fakeGit(outputs map[string]string) *[]*exec.MockCmd {
+	var cmds []*exec.MockCmd
+
+	original := exec.GetCommand()
+	DeferCleanup(exec.SetCommand, original)
+
+	exec.SetCommand(func(name string, args ...string) exec.Cmd {
+		cmd := exec.NewMockCmd([]byte(outputs[strings.Join(args, " ")]), nil).(*exec.MockCmd)
+		cmd.Args = append([]string{name}, args...)
+		cmds = append(cmds, cmd)
+		return cmd
+	})
+	return &cmds
+}
+
+// readStdin returns what was passed to a mock command's stdin.
+func readStdin(cmd *exec.MockCmd) string {
+	b, err := io.ReadAll(cmd.Stdin)
+	Expect(err).NotTo(HaveOccurred())
+	return string(b)
+}
+
+var _ = Describe("GenerateCommitMessage", func() {
+	It("sends the staged changes to the configured provider", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		cfg.Message = "users asked for it"
+
+		message, err := git_auto_commit.GenerateCommitMessage(
+			context.Background(), cfg, "+hello world",
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(Equal("Add greeting"))
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Model).To(Equal("fake-model"))
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("+hello world"))
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("users asked for it"))
+	})
+
+	It("returns provider errors", func() {
+		fake := &fakeCompleter{err: errors.New("boom")}
+		cfg := registerFake(fake)
+
+		message, err := git_auto_commit.GenerateCommitMessage(
+			context.Background(), cfg, "+hello world",
+		)
+		Expect(err).To(MatchError("boom"))
+		Expect(message).To(BeEmpty())
+	})
+
+	It("returns an error for an unknown provider", func() {
+		cfg := &git_auto_commit.Config{
+			Config: &config.Config{Provider: "nonexistent"},
+		}
+
+		_, err := git_auto_commit.GenerateCommitMessage(
+			context.Background(), cfg, "+hello world",
+		)
+		Expect(err).To(MatchError(ContainSubstring("unknown provider")))
+	})
+
+	Context("with the conventional format", func() {
+		const staged = "diff --git a/config/config.go b/config/config.go\n" +
+			"--- a/config/config.go\n" +
+			"+++ b/config/config.go\n" +
+			"@@ -1,2 +1 @@\n" +
+			"-func Load() {}\n" +
+			"+func load() {}\n"
+
+		It("suggests a type, scope, and breaking change in the prompt", func() {
+			fake := &fakeCompleter{responses: []string{"refactor(config)!: unexport Load"}}
+			cfg := registerFake(fake)
+			cfg.Format = "conventional"
+
+			message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, staged)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message).To(Equal("refactor(config)!: unexport Load"))
+
+			Expect(fake.requests).To(HaveLen(1))
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("Conventional Commits"))
+			Expect(fake.requests[0].Prompt).To(ContainSubstring(`the scope is probably "config"`))
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("exported identifiers were removed:\n  Load"))
+		})
+
+		It("asks for a revision when the header doesn't parse", func() {
+			fake := &fakeCompleter{responses: []string{
+				"Unexport Load",
+				"refactor(config)!: unexport Load",
+			}}
+			cfg := registerFake(fake)
+			cfg.Format = "conventional"
+
+			message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, staged)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message).To(Equal("refactor(config)!: unexport Load"))
+
+			Expect(fake.requests).To(HaveLen(2))
+			Expect(fake.requests[1].Prompt).To(ContainSubstring("Unexport Load"))
+			Expect(fake.requests[1].Prompt).To(ContainSubstring(`header "Unexport Load" is not in the form`))
+		})
+
+		It("gives up after too many invalid revisions", func() {
+			fake := &fakeCompleter{responses: []string{"Unexport Load"}}
+			cfg := registerFake(fake)
+			cfg.Format = "conventional"
+
+			_, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, staged)
+			Expect(err).To(MatchError(ContainSubstring("invalid after 2 revisions")))
+			Expect(fake.requests).To(HaveLen(3))
+		})
+	})
+
+	Context("when the message breaks lint rules", func() {
+		It("repairs what it can without asking the model", func() {
+			fake := &fakeCompleter{responses: []string{"```\n\"Add greeting.\"\n```"}}
+			cfg := registerFake(fake)
+
+			message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message).To(Equal("Add greeting"))
+			Expect(fake.requests).To(HaveLen(1))
+		})
+
+		It("asks for a revision with the violations", func() {
+			fake := &fakeCompleter{responses: []string{
+				"Added a greeting to the output of the hello world program",
+				"Add greeting",
+			}}
+			cfg := registerFake(fake)
+
+			message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message).To(Equal("Add greeting"))
+
+			Expect(fake.requests).To(HaveLen(2))
+			Expect(fake.requests[1].Prompt).To(ContainSubstring("keep it to 50 or fewer (subject-length)"))
+			Expect(fake.requests[1].Prompt).To(ContainSubstring(`("Add", not "Added")`))
+		})
+
+		It("keeps the last revision if the problems persist", func() {
+			fake := &fakeCompleter{responses: []string{"Added greeting"}}
+			cfg := registerFake(fake)
+
+			message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message).To(Equal("Added greeting"))
+			Expect(fake.requests).To(HaveLen(3))
+		})
+
+		It("skips ignored rules", func() {
+			fake := &fakeCompleter{responses: []string{"Added greeting"}}
+			cfg := registerFake(fake)
+			cfg.LintIgnore = []string{"imperative"}
+
+			_, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.requests).To(HaveLen(1))
+		})
+	})
+
+	It("shows the model the style of recent commits, leaving out bots", func() {
+		commit := func(author, message string) string {
+			return "c0ffee\x00beef\x00\x00" + author + "\x00a@example.com\x001700000000 +0000\x00" + message + "\n\x1e\n"
+		}
+		cmds := fakeGit(map[string]string{
+			"log --no-merges --date=raw --max-count=10 --format=%H%x00%T%x00%P%x00%an%x00%ae%x00%ad%x00%B%x1e HEAD --": commit("Ada", "parser: handle empty input") +
+				commit("dependabot[bot]", "Bump golang.org/x/net from 0.1.0 to 0.2.0") +
+				commit("Ada", "lexer: add unicode support") +
+				commit("Bob", "parser: report line numbers") +
+				commit("Bob", "Merge branch 'main' into parser") +
+				commit("Ada", "docs: describe the grammar") +
+				commit("Bob", "cli: add a version flag"),
+		})
+
+		fake := &fakeCompleter{responses: []string{"parser: add greeting"}}
+		cfg := registerFake(fake)
+		cfg.StyleSamples = 10
+
+		for range 2 {
+			_, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		prompt := fake.requests[0].Prompt
+		Expect(prompt).To(ContainSubstring("Subjects start with the area they change and a colon"))
+		Expect(prompt).To(ContainSubstring("<example>\nparser: handle empty input\n</example>"))
+		Expect(prompt).NotTo(ContainSubstring("Bump golang.org"))
+		Expect(prompt).NotTo(ContainSubstring("Merge branch"))
+
+		// The style is only learned once.
+		logs := 0
+		for _, cmd := range *cmds {
+			if cmd.Args[1] == "log" {
+				logs++
+			}
+		}
+		Expect(logs).To(Equal(1))
+	})
+
+	It("doesn't ask for the imperative mood when the history doesn't use it", func() {
+		var history string
+		for _, subject := range []string{"Added search", "Fixed the layout", "Updated the README", "Added pagination", "Changed the linter config"} {
+			history += "c0ffee\x00beef\x00\x00Ada\x00a@example.com\x001700000000 +0000\x00" + subject + "\n\x1e\n"
+		}
+		fakeGit(map[string]string{
+			"log --no-merges --date=raw --max-count=10 --format=%H%x00%T%x00%P%x00%an%x00%ae%x00%ad%x00%B%x1e HEAD --": history,
+		})
+
+		fake := &fakeCompleter{responses: []string{"Added greeting"}}
+		cfg := registerFake(fake)
+		cfg.StyleSamples = 10
+
+		message, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(Equal("Added greeting"))
+		Expect(fake.requests).To(HaveLen(1))
+	})
+
+	It("shows the model the team's commit template", func() {
+		tmpl := filepath.Join(GinkgoT().TempDir(), "template")
+		Expect(os.WriteFile(tmpl, []byte("Summary\n\n# Refs: ABC-123\n"), 0o644)).To(Succeed())
+		fakeGit(map[string]string{"config --get --type=path commit.template": tmpl + "\n"})
+
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+
+		_, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("<template>\nSummary\n\n# Refs: ABC-123\n\n</template>"))
+	})
+
+	It("rejects unknown formats", func() {
+		cfg := registerFake(&fakeCompleter{responses: []string{"Add greeting"}})
+		cfg.Format = "haiku"
+
+		_, err := git_auto_commit.GenerateCommitMessage(context.Background(), cfg, "+hello world")
+		Expect(err).To(MatchError(ContainSubstring(`unknown commit message format "haiku"`)))
+	})
+})
+
+var _ = Describe("GenerateCommitMessages", func() {
+	It("requests each candidate with a different temperature", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+
+		messages, err := git_auto_commit.GenerateCommitMessages(
+			context.Background(), cfg, "+hello world", 3,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(HaveLen(3))
+
+		Expect(fake.requests).To(HaveLen(3))
+		temperatures := map[float64]bool{}
+		for _, req := range fake.requests {
+			Expect(req.Prompt).To(ContainSubstring("+hello world"))
+			Expect(req.Temperature).NotTo(BeNil())
+			temperatures[*req.Temperature] = true
+		}
+		Expect(temperatures).To(HaveLen(3))
+	})
+
+	It("returns the first provider error", func() {
+		fake := &fakeCompleter{err: errors.New("boom")}
+		cfg := registerFake(fake)
+
+		messages, err := git_auto_commit.GenerateCommitMessages(
+			context.Background(), cfg, "+hello world", 2,
+		)
+		Expect(err).To(MatchError("boom"))
+		Expect(messages).To(BeNil())
+	})
+
+	It("refuses to send more than MaxCandidates requests", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+
+		_, err := git_auto_commit.GenerateCommitMessages(
+			context.Background(), cfg, "+hello world", git_auto_commit.MaxCandidates+1,
+		)
+		Expect(err).To(MatchError(ContainSubstring("can't generate more than 10 candidates")))
+		Expect(fake.requests).To(BeEmpty())
+	})
+})
+
+var _ = Describe("AutoCommit", func() {
+	It("commits the generated message", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.ExtraArgs = []string{"--no-verify"}
+
+		cmds := fakeGit(map[string]string{
+			"diff --cached": "+hello world",
+		})
+
+		Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+		last := (*cmds)[len(*cmds)-1]
+		Expect(last.Args).To(Equal([]string{"git", "commit", "--file", "-", "--no-verify"}))
+		Expect(readStdin(last)).To(Equal("Add greeting"))
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("+hello world"))
+	})
+
+	Context("when the diff is over budget with the summarize strategy", func() {
+		// goFile returns a diff that adds n copies of line to path.
+		goFile := func(path, line string, n int) string {
+			return "diff --git a/" + path + " b/" + path + "\n" +
+				"--- a/" + path + "\n+++ b/" + path + "\n@@ -1 +1,2 @@\n package main\n" +
+				strings.Repeat("+"+line+"\n", n)
+		}
+		staged := goFile("hello.go", "// hello world", 20) +
+			goFile("bye.go", "// goodbye world", 20) +
+			"diff --git a/go.sum b/go.sum\n" +
+			"--- a/go.sum\n+++ b/go.sum\n@@ -1 +1,2 @@\n a v1\n+b v2\n"
+
+		It("summarizes each batch of files first", func() {
+			fake := &fakeCompleter{responses: []string{
+				"Adds a greeting.",
+				"Adds a farewell.",
+				"Add greeting and farewell",
+			}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.TokenBudget = 150
+			cfg.DiffStrategy = "summarize"
+
+			cmds := fakeGit(map[string]string{"diff --cached": staged})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			Expect(fake.requests).To(HaveLen(3))
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("+// hello world"))
+			Expect(fake.requests[1].Prompt).To(ContainSubstring("+// goodbye world"))
+
+			final := fake.requests[2].Prompt
+			Expect(final).To(ContainSubstring("### hello.go (+20 -0)\n\nAdds a greeting."))
+			Expect(final).To(ContainSubstring("### bye.go (+20 -0)\n\nAdds a farewell."))
+			Expect(final).To(ContainSubstring("go.sum | +1 -0 (generated)"))
+			Expect(final).NotTo(ContainSubstring("+// hello world"))
+
+			last := (*cmds)[len(*cmds)-1]
+			Expect(readStdin(last)).To(Equal("Add greeting and farewell"))
+		})
+
+		It("limits the number of requests, listing the rest of the files", func() {
+			var many string
+			for i := range 20 {
+				many += goFile(fmt.Sprintf("f%02d.go", i), "// a line in this file", 60)
+			}
+
+			fake := &fakeCompleter{responses: []string{"Changes it."}}
+			cfg := registerFake(fake)
+			cfg.DryRun = true
+			cfg.Output = filepath.Join(GinkgoT().TempDir(), "message")
+			cfg.TokenBudget = 400
+			cfg.DiffStrategy = "summarize"
+			cfg.LintIgnore = []string{"all"}
+
+			fakeGit(map[string]string{"diff --cached": many})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			Expect(fake.requests).To(HaveLen(17))
+			final := fake.requests[16].Prompt
+			Expect(final).To(ContainSubstring("### f15.go (+60 -0)\n\nChanges it."))
+			Expect(final).To(ContainSubstring("f16.go | +60 -0 (not summarized)"))
+			Expect(final).To(ContainSubstring("f19.go | +60 -0 (not summarized)"))
+		})
+
+		It("truncates the diff when the summaries are still over budget", func() {
+			fake := &fakeCompleter{responses: []string{
+				strings.Repeat("A very long summary. ", 40),
+				strings.Repeat("A very long summary. ", 40),
+				"Add greeting and farewell",
+			}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.TokenBudget = 150
+			cfg.DiffStrategy = "summarize"
+
+			fakeGit(map[string]string{"diff --cached": staged})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			final := fake.requests[2].Prompt
+			Expect(final).NotTo(ContainSubstring("A very long summary."))
+			Expect(final).To(ContainSubstring("+// hello world"))
+			Expect(final).To(ContainSubstring("bye.go | +20 -0 (too large)"))
+		})
+	})
+
+	It("leaves excluded paths out of the prompt, mentioning them by name", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Exclude = []string{"go.sum"}
+
+		top := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(top, ".autocommitignore"),
+			[]byte("# secrets\n.env\n"), 0o644)).To(Succeed())
+
+		fakeGit(map[string]string{
+			"rev-parse --show-toplevel": top + "\n",
+			"diff --cached -- :(top) " +
+				":(top,exclude,glob)**/go.sum :(top,exclude,glob)**/go.sum/** " +
+				":(top,exclude,glob)**/.env :(top,exclude,glob)**/.env/**": "+hello world",
+			"diff --cached --name-only -- " +
+				":(top,glob)**/go.sum :(top,glob)**/go.sum/** " +
+				":(top,glob)**/.env :(top,glob)**/.env/**": "go.sum\n.env\n",
+		})
+
+		Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+		prompt := fake.requests[0].Prompt
+		Expect(prompt).To(ContainSubstring("+hello world"))
+		Expect(prompt).To(ContainSubstring("contents are not shown:\n\n- go.sum\n- .env\n"))
+	})
+
+	Context("with a dry run", func() {
+		var output string
+
+		BeforeEach(func() {
+			output = filepath.Join(GinkgoT().TempDir(), "message")
+		})
+
+		It("writes the message without committing", func() {
+			fake := &fakeCompleter{responses: []string{"Add greeting"}}
+			cfg := registerFake(fake)
+			cfg.DryRun = true
+			cfg.Output = output
+
+			cmds := fakeGit(map[string]string{"diff --cached": "+hello world"})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			text, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(text)).To(Equal("Add greeting\n"))
+			for _, cmd := range *cmds {
+				Expect(cmd.Args[1]).NotTo(Equal("commit"))
+			}
+		})
+
+		It("writes JSON with the candidates, model, and token usage", func() {
+			fake := &fakeCompleter{
+				responses: []string{"Add greeting", "Greet the world"},
+				usage:     provider.Usage{InputTokens: 100, OutputTokens: 5},
+			}
+			cfg := registerFake(fake)
+			cfg.DryRun = true
+			cfg.JSON = true
+			cfg.Output = output
+			cfg.Candidates = 2
+
+			fakeGit(map[string]string{"diff --cached": "+hello world"})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			text, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			var result git_auto_commit.Result
+			Expect(json.Unmarshal(text, &result)).To(Succeed())
+			Expect(result.Message).To(Equal(result.Candidates[0]))
+			Expect(result.Candidates).To(ConsistOf("Add greeting", "Greet the world"))
+			Expect(result.Provider).To(Equal("fake"))
+			Expect(result.Model).To(Equal("fake-model"))
+			Expect(result.Usage).To(Equal(git_auto_commit.Usage{Requests: 2, InputTokens: 200, OutputTokens: 10}))
+			Expect(string(text)).To(ContainSubstring(`"input_tokens": 200`))
+			Expect(string(text)).To(ContainSubstring(`"generation_ms": `))
+		})
+
+		It("can't split", func() {
+			cfg := registerFake(&fakeCompleter{responses: []string{"Add greeting"}})
+			cfg.DryRun = true
+			cfg.Split = true
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(MatchError(ContainSubstring("--split")))
+		})
+	})
+
+	Context("with a diff that isn't staged", func() {
+		var output string
+
+		BeforeEach(func() {
+			output = filepath.Join(GinkgoT().TempDir(), "message")
+		})
+
+		It("describes a patch file, skipping its mail headers", func() {
+			patch := filepath.Join(GinkgoT().TempDir(), "greeting.patch")
+			Expect(os.WriteFile(patch, []byte("From c0ffee Mon Sep 17 00:00:00 2001\n"+
+				"Subject: [PATCH] wip\n\n---\n"+
+				"diff --git a/hello.go b/hello.go\n+// hello world\n"), 0o644)).To(Succeed())
+
+			fake := &fakeCompleter{responses: []string{"Add greeting"}}
+			cfg := registerFake(fake)
+			cfg.FromDiff = patch
+			cfg.Output = output
+
+			cmds := fakeGit(nil)
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			text, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(text)).To(Equal("Add greeting\n"))
+
+			prompt := fake.requests[0].Prompt
+			Expect(prompt).To(ContainSubstring("diff --git a/hello.go b/hello.go\n+// hello world"))
+			Expect(prompt).NotTo(ContainSubstring("[PATCH] wip"))
+			for _, cmd := range *cmds {
+				Expect(cmd.Args[1]).NotTo(Equal("diff"))
+				Expect(cmd.Args[1]).NotTo(Equal("commit"))
+			}
+		})
+
+		It("describes a revision range", func() {
+			fake := &fakeCompleter{responses: []string{"Add greeting"}}
+			cfg := registerFake(fake)
+			cfg.FromRange = "main...feature"
+			cfg.Output = output
+
+			fakeGit(map[string]string{"diff main...feature": "+hello world"})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("+hello world"))
+		})
+
+		It("rejects options in place of revisions", func() {
+			cfg := registerFake(&fakeCompleter{responses: []string{"Add greeting"}})
+			cfg.FromRange = "--output=/tmp/x HEAD"
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(MatchError(ContainSubstring("invalid revision range")))
+		})
+
+		It("reports empty diffs", func() {
+			fake := &fakeCompleter{responses: []string{"Add greeting"}}
+			cfg := registerFake(fake)
+			cfg.FromRange = "HEAD HEAD"
+
+			fakeGit(nil)
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(MatchError("there are no changes in HEAD HEAD"))
+			Expect(fake.requests).To(BeEmpty())
+		})
+	})
+
+	Context("when the changes contain secrets", func() {
+		const staged = "diff --git a/.env b/.env\n" +
+			"--- a/.env\n+++ b/.env\n@@ -1 +1,2 @@\n DEBUG=1\n" +
+			"+AWS_ACCESS_KEY_ID=AKIAI44QH8DHBEXAMPLE\n"
+
+		It("masks them before prompting", func() {
+			fake := &fakeCompleter{responses: []string{"Add AWS credentials"}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+
+			fakeGit(map[string]string{"diff --cached": staged})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			prompt := fake.requests[0].Prompt
+			Expect(prompt).To(ContainSubstring("+AWS_ACCESS_KEY_ID=[REDACTED aws-access-key-id]"))
+			Expect(prompt).NotTo(ContainSubstring("AKIAI44QH8DHBEXAMPLE"))
+		})
+
+		It("aborts with their locations when configured to", func() {
+			fake := &fakeCompleter{responses: []string{"Add AWS credentials"}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.Secrets = "abort"
+
+			cmds := fakeGit(map[string]string{"diff --cached": staged})
+
+			err := git_auto_commit.AutoCommit(context.Background(), cfg)
+			Expect(err).To(MatchError(git_auto_commit.ErrSecretsFound))
+			Expect(err).To(MatchError(ContainSubstring(".env:2 (aws-access-key-id)")))
+			Expect(fake.requests).To(BeEmpty())
+			for _, cmd := range *cmds {
+				Expect(cmd.Args[1]).NotTo(Equal("commit"))
+			}
+		})
+
+		It("sends them as is when scanning is off", func() {
+			fake := &fakeCompleter{responses: []string{"Add AWS credentials"}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.Secrets = "off"
+
+			fakeGit(map[string]string{"diff --cached": staged})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("AKIAI44QH8DHBEXAMPLE"))
+		})
+	})
+
+	Context("when amending", func() {
+		It("describes HEAD's full changes and amends it", func() {
+			fake := &fakeCompleter{responses: []string{"Add greeting and farewell"}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.Amend = true
+
+			cmds := fakeGit(map[string]string{
+				"log -1 --format=%B HEAD":          "Add greeting\n",
+				"rev-parse --verify --quiet HEAD^": "abc123\n",
+				"diff --cached HEAD^":              "+hello world\n+goodbye world",
+			})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+			prompt := fake.requests[0].Prompt
+			Expect(prompt).To(ContainSubstring("amending an existing commit"))
+			Expect(prompt).To(ContainSubstring("Add greeting"))
+			Expect(prompt).To(ContainSubstring("+goodbye world"))
+
+			last := (*cmds)[len(*cmds)-1]
+			Expect(last.Args).To(Equal([]string{"git", "commit", "--file", "-", "--amend"}))
+			Expect(readStdin(last)).To(Equal("Add greeting and farewell"))
+		})
+
+		It("treats a passed-through --amend the same way", func() {
+			fake := &fakeCompleter{responses: []string{"Add greeting and farewell"}}
+			cfg := registerFake(fake)
+			cfg.Yes = true
+			cfg.ExtraArgs = []string{"--amend"}
+
+			cmds := fakeGit(map[string]string{
+				"log -1 --format=%B HEAD": "Add greeting\n",
+			})
+
+			Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+			Expect(fake.requests[0].Prompt).To(ContainSubstring("amending an existing commit"))
+
+			last := (*cmds)[len(*cmds)-1]
+			Expect(last.Args).To(Equal([]string{"git", "commit", "--file", "-", "--amend"}))
+		})
+	})
+})
+
+var _ = Describe("Reword", func() {
+	// commitLog returns the `git log` invocation and output ReadCommit expects
+	// for a commit.
+	commitLog := func(rev, id, tree, parent, message string) (string, string) {
+		args := "log -1 --date=raw --format=%H%x00%T%x00%P%x00%an%x00%ae%x00%ad%x00%B " + rev + " --"
+		out := strings.Join([]string{id, tree, parent, "Ivy", "ivy@example.com", "1700000000 +0000", message + "\n\n"}, "\x00")
+		return args, out
+	}
+
+	var outputs map[string]string
+
+	BeforeEach(func() {
+		outputs = map[string]string{
+			"rev-list --reverse c1^..c2":   "c1\nc2\n",
+			"rev-list --merges b0..HEAD":   "",
+			"rev-list --reverse b0..HEAD":  "c1\nc2\nc3\n",
+			"show --format= --patch c1 --": "+first",
+			"show --format= --patch c2 --": "+second",
+			"commit-tree t1 -p b0 -F -":    "n1\n",
+			"commit-tree t2 -p n1 -F -":    "n2\n",
+			"commit-tree t3 -p n2 -F -":    "n3\n",
+		}
+		for _, c := range [][]string{
+			{"HEAD", "c3", "t3", "c2", "Keep me"},
+			{"c1", "c1", "t1", "b0", "wip"},
+			{"c2", "c2", "t2", "c1", "fix"},
+			{"c3", "c3", "t3", "c2", "Keep me"},
+		} {
+			args, out := commitLog(c[0], c[1], c[2], c[3], c[4])
+			outputs[args] = out
+		}
+	})
+
+	It("regenerates each message from its own diff and replays the branch", func() {
+		fake := &fakeCompleter{responses: []string{"Add first", "Add second"}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+
+		cmds := fakeGit(outputs)
+
+		Expect(git_auto_commit.Reword(context.Background(), cfg, "c1^..c2")).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(2))
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("+first"))
+		Expect(fake.requests[1].Prompt).To(ContainSubstring("+second"))
+
+		var trees []*exec.MockCmd
+		for _, cmd := range *cmds {
+			if cmd.Args[1] == "commit-tree" {
+				trees = append(trees, cmd)
+			}
+		}
+		Expect(trees).To(HaveLen(3))
+		Expect(readStdin(trees[0])).To(Equal("Add first\n"))
+		Expect(readStdin(trees[1])).To(Equal("Add second\n"))
+		Expect(readStdin(trees[2])).To(Equal("Keep me\n"))
+		Expect(trees[0].Env).To(ContainElement("GIT_AUTHOR_DATE=1700000000 +0000"))
+
+		last := (*cmds)[len(*cmds)-1]
+		Expect(last.Args).To(Equal([]string{
+			"git", "update-ref", "-m", "auto-commit: reword c1^..c2", "HEAD", "n3", "c3",
+		}))
+	})
+
+	It("refuses to rewrite across merge commits", func() {
+		fake := &fakeCompleter{responses: []string{"Add first"}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+
+		outputs["rev-list --merges b0..HEAD"] = "m1\n"
+		fakeGit(outputs)
+
+		err := git_auto_commit.Reword(context.Background(), cfg, "c1^..c2")
+		Expect(err).To(MatchError(ContainSubstring("merge commit m1")))
+		Expect(fake.requests).To(BeEmpty())
+	})
+
+	It("returns an error for an empty range", func() {
+		cfg := registerFake(&fakeCompleter{})
+		fakeGit(nil)
+
+		err := git_auto_commit.Reword(context.Background(), cfg, "HEAD..HEAD")
+		Expect(err).To(MatchError(`no commits in "HEAD..HEAD"`))
+	})
+
+	It("refuses a single revision, which would reach back to the root", func() {
+		cfg := registerFake(&fakeCompleter{})
+		cmds := fakeGit(nil)
+
+		err := git_auto_commit.Reword(context.Background(), cfg, "HEAD~3")
+		Expect(err).To(MatchError(ContainSubstring(`"HEAD~3" is not a revision range`)))
+		Expect(*cmds).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Split", func() {
+	const staged = `diff --git a/main.go b/main.go
+index 1c99002..ac420dc 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++import "fmt"
+ func main() {
+@@ -31,3 +32,4 @@ func helper() {
+ 	a := 1
++	b := 2
+ }
+diff --git a/README.md b/README.md
+new file mode 100644
+index 0000000..3b18e51
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++hello world
+`
+
+	const stagedArgs = "diff --cached --binary --no-renames --no-color --no-ext-diff"
+
+	It("commits each group of hunks in turn", func() {
+		fake := &fakeCompleter{responses: []string{"```json\n" + `{"commits": [
+			{"message": "Document the project", "hunks": ["3"]},
+			{"message": "Add a helper variable", "hunks": ["2", "1"]}
+		]}` + "\n```"}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Split = true
+
+		cmds := fakeGit(map[string]string{
+			stagedArgs:                        staged,
+			"rev-parse --verify --quiet HEAD": "c0ffee\n",
+			"write-tree":                      "beef\n",
+		})
+
+		Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("### Hunk 3"))
+
+		var steps []string
+		var patches []string
+		for _, cmd := range *cmds {
+			switch cmd.Args[1] {
+			case "read-tree", "apply", "commit":
+				steps = append(steps, strings.Join(cmd.Args[1:], " "))
+			}
+			switch cmd.Args[1] {
+			case "apply", "commit":
+				patches = append(patches, readStdin(cmd))
+			}
+		}
+		Expect(steps).To(Equal([]string{
+			"read-tree HEAD",
+			"apply --cached -",
+			"commit --file -",
+			"apply --cached -",
+			"commit --file -",
+		}))
+
+		Expect(patches[0]).To(HavePrefix("diff --git a/README.md b/README.md\n"))
+		Expect(patches[1]).To(Equal("Document the project"))
+		Expect(patches[2]).To(ContainSubstring("@@ -1,3 +1,4 @@"))
+		Expect(patches[2]).To(ContainSubstring("@@ -31,3 +32,4 @@"))
+		Expect(strings.Index(patches[2], "@@ -1,3")).To(BeNumerically("<", strings.Index(patches[2], "@@ -31,3")))
+		Expect(patches[3]).To(Equal("Add a helper variable"))
+	})
+
+	It("restores the branch and index when a step fails", func() {
+		fake := &fakeCompleter{responses: []string{`{"commits": [
+			{"message": "Document the project", "hunks": ["3"]},
+			{"message": "Add a helper variable", "hunks": ["1", "2"]}
+		]}`}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Split = true
+
+		var args []string
+		applies := 0
+		original := exec.GetCommand()
+		DeferCleanup(exec.SetCommand, original)
+		exec.SetCommand(func(name string, a ...string) exec.Cmd {
+			args = append(args, strings.Join(a, " "))
+			switch strings.Join(a, " ") {
+			case stagedArgs:
+				return exec.NewMockCmd([]byte(staged), nil)
+			case "rev-parse --verify --quiet HEAD":
+				return exec.NewMockCmd([]byte("c0ffee\n"), nil)
+			case "write-tree":
+				return exec.NewMockCmd([]byte("beef\n"), nil)
+			case "apply --cached -":
+				applies++
+				if applies == 2 {
+					return exec.NewMockCmd(nil, errors.New("patch does not apply"))
+				}
+			}
+			return exec.NewMockCmd(nil, nil)
+		})
+
+		err := git_auto_commit.AutoCommit(context.Background(), cfg)
+		Expect(err).To(MatchError(ContainSubstring("failed to stage commit 2 of 2")))
+		Expect(args[len(args)-2:]).To(Equal([]string{
+			"reset --soft c0ffee",
+			"read-tree beef",
+		}))
+	})
+
+	It("revises each message with only its own hunks", func() {
+		fake := &fakeCompleter{responses: []string{
+			`{"commits": [
+				{"message": "Document the project.", "hunks": ["3"]},
+				{"message": "Added a helper variable", "hunks": ["1", "2"]}
+			]}`,
+			"Add a helper variable",
+		}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Split = true
+
+		cmds := fakeGit(map[string]string{
+			stagedArgs:                        staged,
+			"rev-parse --verify --quiet HEAD": "c0ffee\n",
+			"write-tree":                      "beef\n",
+		})
+
+		Expect(git_auto_commit.AutoCommit(context.Background(), cfg)).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(2))
+		revision := fake.requests[1].Prompt
+		Expect(revision).To(ContainSubstring("+\tb := 2"))
+		Expect(revision).NotTo(ContainSubstring("hello world"))
+		Expect(revision).NotTo(ContainSubstring("JSON"))
+
+		var messages []string
+		for _, cmd := range *cmds {
+			if cmd.Args[1] == "commit" {
+				messages = append(messages, readStdin(cmd))
+			}
+		}
+		Expect(messages).To(Equal([]string{"Document the project", "Add a helper variable"}))
+	})
+
+	It("checks for a commit to split onto before planning", func() {
+		fake := &fakeCompleter{responses: []string{`{"commits": []}`}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Split = true
+
+		original := exec.GetCommand()
+		DeferCleanup(exec.SetCommand, original)
+		exec.SetCommand(func(name string, a ...string) exec.Cmd {
+			if strings.Join(a, " ") == "rev-parse --verify --quiet HEAD" {
+				return exec.NewMockCmd(nil, errors.New("exit status 1"))
+			}
+			return exec.NewMockCmd(nil, nil)
+		})
+
+		err := git_auto_commit.AutoCommit(context.Background(), cfg)
+		Expect(err).To(MatchError("--split needs at least one existing commit"))
+		Expect(fake.requests).To(BeEmpty())
+	})
+
+	It("rejects plans that refer to unknown hunks", func() {
+		fake := &fakeCompleter{responses: []string{`{"commits": [{"message": "Oops", "hunks": ["9"]}]}`}}
+		cfg := registerFake(fake)
+		cfg.Yes = true
+		cfg.Split = true
+
+		cmds := fakeGit(map[string]string{stagedArgs: staged})
+
+		err := git_auto_commit.AutoCommit(context.Background(), cfg)
+		Expect(err).To(MatchError(`split plan refers to unknown hunk "9"`))
+		for _, cmd := range *cmds {
+			Expect(cmd.Args[1]).NotTo(Equal("commit"))
+		}
+	})
+})
+
+var _ = Describe("Hook", func() {
+	var hooks, msgFile string
+
+	BeforeEach(func() {
+		hooks = filepath.Join(GinkgoT().TempDir(), "hooks")
+		msgFile = filepath.Join(GinkgoT().TempDir(), "COMMIT_EDITMSG")
+		Expect(os.WriteFile(msgFile, []byte("\n# Please enter the commit message for your changes.\n"), 0o644)).To(Succeed())
+	})
+
+	It("writes the generated message above Git's comments", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		fakeGit(map[string]string{"diff --cached": "+hello world"})
+
+		Expect(git_auto_commit.RunHook(context.Background(), cfg, msgFile, "")).To(Succeed())
+
+		text, err := os.ReadFile(msgFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(text)).To(Equal("Add greeting\n\n# Please enter the commit message for your changes.\n"))
+		Expect(fake.requests[0].Prompt).To(ContainSubstring("+hello world"))
+	})
+
+	It("replaces the team's template, keeping its comments", func() {
+		tmpl := filepath.Join(GinkgoT().TempDir(), "template")
+		Expect(os.WriteFile(tmpl, []byte("Summary\n\n# Refs: ABC-123\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(msgFile, []byte("Summary\n\n# Refs: ABC-123\n\n# Please enter the commit message.\n"), 0o644)).To(Succeed())
+
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		fakeGit(map[string]string{
+			"diff --cached": "+hello world",
+			"config --get --type=path commit.template": tmpl + "\n",
+		})
+
+		Expect(git_auto_commit.RunHook(context.Background(), cfg, msgFile, "template")).To(Succeed())
+
+		text, err := os.ReadFile(msgFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(text)).To(Equal("Add greeting\n\n# Refs: ABC-123\n\n# Please enter the commit message.\n"))
+	})
+
+	DescribeTable("leaves commits that already have a message alone",
+		func(source string) {
+			fake := &fakeCompleter{responses: []string{"Add greeting"}}
+			cfg := registerFake(fake)
+			fakeGit(map[string]string{"diff --cached": "+hello world"})
+
+			Expect(git_auto_commit.RunHook(context.Background(), cfg, msgFile, source)).To(Succeed())
+
+			text, err := os.ReadFile(msgFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(text)).NotTo(ContainSubstring("Add greeting"))
+			Expect(fake.requests).To(BeEmpty())
+		},
+		Entry("git commit -m", "message"),
+		Entry("merges", "merge"),
+		Entry("squashes", "squash"),
+		Entry("amends", "commit"),
+	)
+
+	It("does nothing when nothing is staged", func() {
+		fake := &fakeCompleter{responses: []string{"Add greeting"}}
+		cfg := registerFake(fake)
+		fakeGit(nil)
+
+		Expect(git_auto_commit.RunHook(context.Background(), cfg, msgFile, "")).To(Succeed())
+		Expect(fake.requests).To(BeEmpty())
+	})
+
+	It("installs and uninstalls itself", func() {
+		fakeGit(map[string]string{"rev-parse --git-path hooks": hooks + "\n"})
+		path := filepath.Join(hooks, "prepare-commit-msg")
+
+		var out strings.Builder
+		Expect(git_auto_commit.InstallHook(&out)).To(Succeed())
+		Expect(git_auto_commit.InstallHook(&out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("Installed " + path))
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm() & 0o111).NotTo(BeZero())
+		script, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(script)).To(ContainSubstring(`git-auto-commit hook run "$@"`))
+
+		Expect(git_auto_commit.UninstallHook(&out)).To(Succeed())
+		Expect(path).NotTo(BeAnExistingFile())
+	})
+
+	It("leaves other hooks alone", func() {
+		fakeGit(map[string]string{"rev-parse --git-path hooks": hooks + "\n"})
+		path := filepath.Join(hooks, "prepare-commit-msg")
+		Expect(os.MkdirAll(hooks, 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755)).To(Succeed())
+
+		Expect(git_auto_commit.InstallHook(io.Discard)).To(MatchError(ContainSubstring("already exists")))
+		Expect(git_auto_commit.UninstallHook(io.Discard)).To(MatchError(ContainSubstring("wasn't installed by git-auto-commit")))
+		Expect(path).To(BeAnExistingFile())
+	})
+})
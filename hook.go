@@ -0,0 +1,167 @@
+package git_auto_commit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+const (
+	hookName   = "prepare-commit-msg"
+	hookMarker = "# Installed by git-auto-commit."
+)
+
+// hookScript is the prepare-commit-msg hook that InstallHook writes. It does
+// nothing if git-auto-commit isn't installed, so that commits still work.
+const hookScript = `#!/bin/sh
+` + hookMarker + ` Remove with "git auto-commit hook uninstall".
+command -v git-auto-commit >/dev/null 2>&1 || exit 0
+exec git-auto-commit hook run "$@"
+`
+
+// hookPath returns the path of the repository's prepare-commit-msg hook.
+func hookPath() (string, error) {
+	dir, err := git.HooksDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the hooks directory: %w", err)
+	}
+	return filepath.Join(dir, hookName), nil
+}
+
+// ownHook reports whether the hook at path was installed by InstallHook.
+func ownHook(path string) (bool, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(text), hookMarker), nil
+}
+
+// InstallHook installs a prepare-commit-msg hook that generates a message
+// whenever `git commit` is run without one, and writes its path to w. It
+// returns an error rather than replace a hook it didn't install.
+func InstallHook(w io.Writer) error {
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+
+	own, err := ownHook(path)
+	if err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+	if err == nil && !own {
+		return fmt.Errorf("a %s hook already exists at %s; remove it or add %q to it",
+			hookName, path, `git-auto-commit hook run "$@"`)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(hookScript), 0o755); err != nil {
+		log.Errorw("failed to write hook",
+			"path", path,
+			"error", err)
+		return err
+	}
+	fmt.Fprintf(w, "Installed %s\n", path)
+	return nil
+}
+
+// UninstallHook removes the hook installed by InstallHook and writes its path
+// to w. It leaves hooks it didn't install alone.
+func UninstallHook(w io.Writer) error {
+	path, err := hookPath()
+	if err != nil {
+		return err
+	}
+
+	own, err := ownHook(path)
+	if errors.Is(err, iofs.ErrNotExist) {
+		fmt.Fprintf(w, "No %s hook is installed\n", hookName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !own {
+		return fmt.Errorf("the %s hook at %s wasn't installed by git-auto-commit", hookName, path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Removed %s\n", path)
+	return nil
+}
+
+// RunHook acts as the prepare-commit-msg hook. Given the path of the message
+// file and the source of the message, it generates a message for the staged
+// changes and writes it to the top of the file, above the status and scissors
+// that Git has already written there. If Git started from commit.template, the
+// generated message replaces it, keeping only its comments. Commits that
+// already have a message, such as merges, squashes, amends, and
+// `git commit -m`, are left alone.
+func RunHook(ctx context.Context, config *Config, path, source string) error {
+	if source != "" && source != "template" {
+		log.Debugw("message already provided, skipping",
+			"source", source)
+		return nil
+	}
+
+	staged, err := stagedChanges(config)
+	if err != nil {
+		log.Errorw("failed to get staged changes",
+			"error", err)
+		return err
+	}
+	if strings.TrimSpace(staged) == "" && len(config.excluded) == 0 {
+		log.Debugw("nothing staged, skipping")
+		return nil
+	}
+
+	if err := redactSecrets(config, &staged); err != nil {
+		return err
+	}
+	staged, err = fitDiff(ctx, config, staged)
+	if err != nil {
+		log.Errorw("failed to fit staged changes to the token budget",
+			"error", err)
+		return err
+	}
+
+	message, err := GenerateCommitMessage(ctx, config, staged)
+	if err != nil {
+		log.Errorw("failed to generate commit message",
+			"error", err)
+		return err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+
+	rest := string(existing)
+	if tmpl := commitTemplate(config); source == "template" && strings.HasPrefix(rest, tmpl) {
+		char, err := readCommentChar(message)
+		if err != nil {
+			return err
+		}
+		rest = templateComments(tmpl, char) + strings.TrimLeft(rest[len(tmpl):], "\n")
+	}
+
+	text := message + "\n"
+	if rest = strings.TrimLeft(rest, "\n"); rest != "" {
+		text += "\n" + rest
+	}
+	return os.WriteFile(path, []byte(text), 0o644)
+}
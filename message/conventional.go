@@ -0,0 +1,146 @@
+// Package message parses and checks commit messages, and infers what a
+// message should say about a diff.
+package message
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Types are the Conventional Commits types that messages may use, following
+// the Angular convention.
+var Types = []string{
+	"build",
+	"chore",
+	"ci",
+	"docs",
+	"feat",
+	"fix",
+	"perf",
+	"refactor",
+	"revert",
+	"style",
+	"test",
+}
+
+var (
+	// conventionalHeader matches "type(scope)!: description", where the scope
+	// and "!" are optional.
+	conventionalHeader = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()\s][^()]*)\))?(!)?: (.*)$`)
+
+	// footerLine matches the first line of a footer, such as "Refs: #123",
+	// "Closes #42", or "BREAKING CHANGE: removed Foo".
+	footerLine = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z-]*)(?:: | #)`)
+)
+
+// Footer is a trailer at the end of a Conventional Commits message, such as
+// "BREAKING CHANGE: ..." or "Refs: #123".
+type Footer struct {
+	Token string
+	Value string
+}
+
+// Conventional is a commit message in the Conventional Commits format.
+type Conventional struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Body        string
+	Footers     []Footer
+}
+
+// ParseConventional parses msg as a Conventional Commits message. It returns
+// an error describing the first problem if the header doesn't follow the
+// spec or uses a type other than one of Types.
+func ParseConventional(msg string) (*Conventional, error) {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	header := strings.TrimSpace(lines[0])
+	if header == "" {
+		return nil, errors.New("the message is empty")
+	}
+
+	m := conventionalHeader.FindStringSubmatch(header)
+	if m == nil {
+		return nil, fmt.Errorf(`header %q is not in the form "type(scope): description"`, header)
+	}
+	c := &Conventional{
+		Type:        m[1],
+		Scope:       m[2],
+		Breaking:    m[3] != "",
+		Description: strings.TrimSpace(m[4]),
+	}
+	if !slices.Contains(Types, c.Type) {
+		return nil, fmt.Errorf("type %q is not one of %s", c.Type, strings.Join(Types, ", "))
+	}
+	if c.Description == "" {
+		return nil, fmt.Errorf("header %q has no description", header)
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		return nil, errors.New("the header must be followed by a blank line")
+	}
+
+	paragraphs := splitParagraphs(lines[1:])
+	if n := len(paragraphs); n > 0 && footerLine.MatchString(paragraphs[n-1][0]) {
+		c.Footers = parseFooters(paragraphs[n-1])
+		paragraphs = paragraphs[:n-1]
+	}
+	for _, f := range c.Footers {
+		if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+			c.Breaking = true
+		}
+	}
+
+	body := make([]string, len(paragraphs))
+	for i, p := range paragraphs {
+		body[i] = strings.Join(p, "\n")
+	}
+	c.Body = strings.Join(body, "\n\n")
+
+	return c, nil
+}
+
+// splitParagraphs groups lines into paragraphs separated by blank lines.
+func splitParagraphs(lines []string) [][]string {
+	var (
+		paragraphs [][]string
+		current    []string
+	)
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if current != nil {
+				paragraphs = append(paragraphs, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if current != nil {
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
+
+// parseFooters parses the footers in a message's last paragraph. Lines that
+// don't start a footer continue the one before them.
+func parseFooters(lines []string) []Footer {
+	var footers []Footer
+	for _, line := range lines {
+		m := footerLine.FindStringSubmatch(line)
+		if m == nil {
+			last := &footers[len(footers)-1]
+			last.Value += "\n" + line
+			continue
+		}
+		footers = append(footers, Footer{
+			Token: m[1],
+			Value: strings.TrimSpace(line[len(m[0]):]),
+		})
+	}
+	return footers
+}
@@ -0,0 +1,162 @@
+package message
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/util/diff"
+)
+
+// Hint is what a diff suggests about the header of a Conventional Commits
+// message. It's only a suggestion: the model still reads the diff itself.
+type Hint struct {
+	// Type is the commit type implied by the kinds of files changed, or ""
+	// if they don't settle it.
+	Type string
+
+	// Scope is the top-level package that all of the changes are in, or ""
+	// if they span several packages or touch the repository root.
+	Scope string
+
+	// Removed lists the exported Go identifiers that the diff deletes without
+	// adding back, such as "Config" or "Engine.Lookup".
+	Removed []string
+}
+
+// Breaking reports whether the diff appears to break the public API.
+func (h *Hint) Breaking() bool {
+	return len(h.Removed) > 0
+}
+
+// declaration matches the name of an exported top-level Go function, method,
+// type, variable, or constant on an added or removed diff line. Methods are
+// matched with their receiver's type.
+var declaration = regexp.MustCompile(
+	`^[+-](?:func (?:\(\w+ \*?([A-Z]\w*)(?:\[[^\]]*\])?\) )?|type |var |const )([A-Z]\w*)`)
+
+// Infer guesses the type, scope, and breaking changes of the given files.
+func Infer(files []*diff.File) *Hint {
+	hint := &Hint{}
+	if len(files) == 0 {
+		return hint
+	}
+
+	kinds := make(map[string]bool)
+	scopes := make(map[string]bool)
+	feature := false
+	for _, f := range files {
+		kind := fileKind(f.Path())
+		kinds[kind] = true
+		if kind == "" && f.IsNew() {
+			feature = true
+		}
+		scopes[scopeOf(f.Path())] = true
+	}
+
+	switch {
+	case len(kinds) == 1 && !kinds[""]:
+		for kind := range kinds {
+			hint.Type = kind
+		}
+	case feature:
+		hint.Type = "feat"
+	}
+
+	if len(scopes) == 1 {
+		for scope := range scopes {
+			hint.Scope = scope
+		}
+	}
+
+	hint.Removed = removedAPIs(files)
+	return hint
+}
+
+// docFiles are file names that are documentation wherever they are.
+var docFiles = []string{"AUTHORS", "CHANGELOG", "CONTRIBUTING", "LICENSE", "NOTICE", "README"}
+
+// buildFiles are file names that belong to the build system.
+var buildFiles = []string{
+	"Dockerfile",
+	"Makefile",
+	"go.mod",
+	"go.sum",
+	"package.json",
+	"package-lock.json",
+	"pnpm-lock.yaml",
+	"yarn.lock",
+}
+
+// fileKind returns the commit type implied by changing a file on its own:
+// "docs", "test", "ci", or "build". It returns "" for source files.
+func fileKind(p string) string {
+	base := path.Base(p)
+	dirs := strings.Split(path.Dir(p), "/")
+	ext := path.Ext(base)
+
+	switch {
+	case strings.HasPrefix(p, ".github/workflows/") || strings.HasPrefix(p, ".circleci/") ||
+		base == ".gitlab-ci.yml" || base == ".travis.yml":
+		return "ci"
+	case slices.Contains(buildFiles, base) || strings.HasPrefix(base, ".goreleaser"):
+		return "build"
+	case strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") || slices.Contains(dirs, "testdata"):
+		return "test"
+	case ext == ".md" || ext == ".rst" || ext == ".adoc" || dirs[0] == "docs" ||
+		slices.Contains(docFiles, strings.TrimSuffix(base, ext)):
+		return "docs"
+	}
+	return ""
+}
+
+// scopeOf returns the top-level directory of a path, or "" for files in the
+// repository root and in hidden directories.
+func scopeOf(p string) string {
+	dir, _, ok := strings.Cut(p, "/")
+	if !ok || strings.HasPrefix(dir, ".") {
+		return ""
+	}
+	return dir
+}
+
+// removedAPIs returns the exported declarations that are deleted from
+// non-test Go files and not declared again anywhere in the diff, so that
+// moved and reformatted declarations don't count. Packages under "internal"
+// and "cmd" aren't importable, so they're skipped.
+func removedAPIs(files []*diff.File) []string {
+	var (
+		removed []string
+		added   = make(map[string]bool)
+	)
+	for _, f := range files {
+		p := f.Path()
+		dirs := strings.Split(path.Dir(p), "/")
+		public := path.Ext(p) == ".go" && !strings.HasSuffix(p, "_test.go") &&
+			!slices.Contains(dirs, "internal") && dirs[0] != "cmd"
+
+		for _, h := range f.Hunks {
+			for _, line := range h.Lines[1:] {
+				m := declaration.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				name := m[2]
+				if m[1] != "" {
+					name = m[1] + "." + name
+				}
+				if line[0] == '+' {
+					added[name] = true
+				} else if public && !slices.Contains(removed, name) {
+					removed = append(removed, name)
+				}
+			}
+		}
+	}
+
+	return slices.DeleteFunc(removed, func(name string) bool {
+		return added[name]
+	})
+}
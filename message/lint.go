@@ -0,0 +1,330 @@
+package message
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lint rule names, as given to NewLinter to ignore them.
+const (
+	RuleFences         = "fences"
+	RuleQuotes         = "quotes"
+	RuleTrailingPeriod = "trailing-period"
+	RuleBlankLine      = "blank-line"
+	RuleBodyWrap       = "body-wrap"
+	RuleSubjectLength  = "subject-length"
+	RuleImperative     = "imperative"
+)
+
+// Rules lists every lint rule, in the order they're applied.
+var Rules = []string{
+	RuleFences,
+	RuleQuotes,
+	RuleTrailingPeriod,
+	RuleBlankLine,
+	RuleBodyWrap,
+	RuleSubjectLength,
+	RuleImperative,
+}
+
+// Violation is a problem a lint rule found in a message.
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// String describes the violation for a person or a model to fix.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s)", v.Message, v.Rule)
+}
+
+// Linter checks commit messages against the lint rules, and repairs the
+// problems that have an unambiguous fix: Markdown fences, surrounding quotes,
+// a trailing period on the subject, a missing blank line after it, and long
+// body lines. Long subjects and subjects that aren't in the imperative mood
+// can only be reported.
+type Linter struct {
+	// SubjectLength is the maximum number of characters in the subject.
+	SubjectLength int
+
+	// BodyWidth is the column that body lines are wrapped at.
+	BodyWidth int
+
+	ignore []string
+}
+
+// NewLinter returns a Linter with the given limits that skips the ignored
+// rules. Ignoring "all" disables every rule. It returns an error if a rule
+// name is unknown.
+func NewLinter(subjectLength, bodyWidth int, ignore []string) (*Linter, error) {
+	for _, name := range ignore {
+		if name != "all" && !slices.Contains(Rules, name) {
+			return nil, fmt.Errorf("unknown lint rule %q (expected one of %s)",
+				name, strings.Join(Rules, ", "))
+		}
+	}
+	return &Linter{
+		SubjectLength: subjectLength,
+		BodyWidth:     bodyWidth,
+		ignore:        ignore,
+	}, nil
+}
+
+// enabled reports whether the rule should run.
+func (l *Linter) enabled(rule string) bool {
+	return !slices.Contains(l.ignore, "all") && !slices.Contains(l.ignore, rule)
+}
+
+// Fix repairs what it can in msg and trims surrounding whitespace.
+func (l *Linter) Fix(msg string) string {
+	msg = strings.TrimSpace(msg)
+
+	if l.enabled(RuleFences) {
+		msg = strings.TrimSpace(stripFences(msg))
+	}
+	if l.enabled(RuleQuotes) {
+		msg = strings.TrimSpace(stripQuotes(msg))
+	}
+
+	lines := strings.Split(msg, "\n")
+	if l.enabled(RuleTrailingPeriod) && hasTrailingPeriod(lines[0]) {
+		lines[0] = strings.TrimSuffix(lines[0], ".")
+	}
+	if l.enabled(RuleBlankLine) && len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		lines = slices.Insert(lines, 1, "")
+	}
+	if l.enabled(RuleBodyWrap) && l.BodyWidth > 0 && len(lines) > 1 {
+		var body []string
+		for _, line := range lines[1:] {
+			body = append(body, wrapLine(line, l.BodyWidth)...)
+		}
+		lines = append(lines[:1], body...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Lint returns the rules that msg violates, in the order of Rules.
+func (l *Linter) Lint(msg string) []Violation {
+	var (
+		violations []Violation
+		lines      = strings.Split(strings.TrimSpace(msg), "\n")
+		subject    = lines[0]
+	)
+	report := func(rule, format string, args ...any) {
+		if l.enabled(rule) {
+			violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	if slices.ContainsFunc(lines, isFence) {
+		report(RuleFences, "the message contains a Markdown code fence")
+	}
+	if quote, ok := surroundingQuote(strings.TrimSpace(msg)); ok {
+		report(RuleQuotes, "the message is wrapped in %s quotes", quote)
+	}
+	if hasTrailingPeriod(subject) {
+		report(RuleTrailingPeriod, "the subject ends with a period")
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		report(RuleBlankLine, "the subject isn't followed by a blank line")
+	}
+	if l.BodyWidth > 0 {
+		for i, line := range lines[1:] {
+			if wrappable(line) && utf8.RuneCountInString(line) > l.BodyWidth {
+				report(RuleBodyWrap, "body line %d is longer than %d characters", i+2, l.BodyWidth)
+				break
+			}
+		}
+	}
+	if n := utf8.RuneCountInString(subject); l.SubjectLength > 0 && n > l.SubjectLength {
+		report(RuleSubjectLength, "the subject is %d characters long; keep it to %d or fewer",
+			n, l.SubjectLength)
+	}
+	if word, base, ok := notImperative(subject); ok {
+		report(RuleImperative, "the subject should use the imperative mood (%q, not %q)",
+			base, word)
+	}
+
+	return violations
+}
+
+// isFence reports whether line opens or closes a Markdown code block.
+func isFence(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "```")
+}
+
+// stripFences removes a Markdown code block wrapped around the whole message.
+func stripFences(msg string) string {
+	lines := strings.Split(msg, "\n")
+	if len(lines) < 2 || !isFence(lines[0]) {
+		return msg
+	}
+	lines = lines[1:]
+	if isFence(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// surroundingQuote returns the kind of quote msg is wrapped in, if any.
+func surroundingQuote(msg string) (string, bool) {
+	for _, quote := range []string{`"`, `'`, "`"} {
+		// Quotes inside the message mean the outer ones aren't a pair.
+		if len(msg) > 1 && strings.HasPrefix(msg, quote) && strings.HasSuffix(msg, quote) &&
+			strings.Count(msg, quote) == 2 {
+			return quote, true
+		}
+	}
+	return "", false
+}
+
+// stripQuotes removes quotes wrapped around the whole message.
+func stripQuotes(msg string) string {
+	if quote, ok := surroundingQuote(msg); ok {
+		return msg[len(quote) : len(msg)-len(quote)]
+	}
+	return msg
+}
+
+// hasTrailingPeriod reports whether the subject ends with a single period,
+// unlike an ellipsis.
+func hasTrailingPeriod(subject string) bool {
+	return strings.HasSuffix(subject, ".") && !strings.HasSuffix(subject, "..")
+}
+
+// listItem matches the marker of a bullet or numbered list item.
+var listItem = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)`)
+
+// wrappable reports whether a body line is prose that can be wrapped. Code
+// (indented by a tab or four spaces) and lines without spaces, such as URLs,
+// are left alone.
+func wrappable(line string) bool {
+	if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+		return false
+	}
+	text := strings.TrimSpace(line)
+	if m := listItem.FindString(line); m != "" {
+		text = line[len(m):]
+	}
+	return strings.Contains(strings.TrimSpace(text), " ")
+}
+
+// wrapLine breaks a line longer than width at spaces. Continuation lines of
+// list items are indented to line up with the item's text.
+func wrapLine(line string, width int) []string {
+	if !wrappable(line) || utf8.RuneCountInString(line) <= width {
+		return []string{line}
+	}
+
+	indent := ""
+	if m := listItem.FindString(line); m != "" {
+		indent = strings.Repeat(" ", utf8.RuneCountInString(m))
+	} else {
+		indent = line[:len(line)-len(strings.TrimLeft(line, " "))]
+	}
+
+	var (
+		wrapped []string
+		current string
+	)
+	for _, word := range strings.Fields(line) {
+		switch {
+		case current == "":
+			current = line[:len(line)-len(strings.TrimLeft(line, " "))] + word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width:
+			wrapped = append(wrapped, current)
+			current = indent + word
+		default:
+			current += " " + word
+		}
+	}
+	return append(wrapped, current)
+}
+
+// verbs are common commit verbs in their imperative form.
+var verbs = []string{
+	"add", "adjust", "allow", "avoid", "bump", "change", "check", "clean",
+	"configure", "convert", "correct", "create", "define", "delete",
+	"deprecate", "disable", "document", "enable", "ensure", "expose",
+	"extract", "fix", "handle", "implement", "improve", "include",
+	"introduce", "limit", "load", "merge", "migrate", "move", "optimize",
+	"parse", "prevent", "print", "reduce", "refactor", "remove", "rename",
+	"reorder", "replace", "require", "restore", "return", "revert",
+	"simplify", "sort", "support", "switch", "test",
+	"update", "upgrade", "use", "validate",
+}
+
+// irregularVerbs maps inflected forms that can't be derived by adding a
+// suffix to their imperative form.
+var irregularVerbs = map[string]string{
+	"made": "make", "makes": "make", "making": "make",
+	"wrote": "write", "written": "write", "writes": "write", "writing": "write",
+	"rewrote": "rewrite", "rewrites": "rewrite", "rewriting": "rewrite",
+	"dropped": "drop", "drops": "drop", "dropping": "drop",
+	"formatted": "format", "formats": "format", "formatting": "format",
+	"resets": "reset", "resetting": "reset",
+	"sets": "set", "setting": "set",
+	"skipped": "skip", "skips": "skip", "skipping": "skip",
+	"splits": "split", "splitting": "split",
+	"stopped": "stop", "stops": "stop", "stopping": "stop",
+	"wrapped": "wrap", "wraps": "wrap", "wrapping": "wrap",
+	"ran": "run", "runs": "run", "running": "run",
+	"logged": "log", "logs": "log", "logging": "log",
+}
+
+// inflections maps the past tense, third person, and gerund forms of verbs
+// to their imperative form.
+var inflections = func() map[string]string {
+	forms := make(map[string]string)
+	for _, verb := range verbs {
+		stem := strings.TrimSuffix(verb, "e")
+		suffixes := []string{"s", "ed", "ing"}
+		if strings.HasSuffix(verb, "e") {
+			suffixes = []string{"s", "d", "ing"}
+		} else if strings.HasSuffix(verb, "x") || strings.HasSuffix(verb, "ch") || strings.HasSuffix(verb, "sh") {
+			suffixes = []string{"es", "ed", "ing"}
+		}
+		for _, suffix := range suffixes {
+			if suffix == "ing" {
+				forms[stem+suffix] = verb
+			} else {
+				forms[verb+suffix] = verb
+			}
+		}
+	}
+	for form, verb := range irregularVerbs {
+		forms[form] = verb
+	}
+	return forms
+}()
+
+// conventionalPrefix matches the "type(scope)!: " part of a Conventional
+// Commits header.
+var conventionalPrefix = regexp.MustCompile(`^[A-Za-z]+(?:\([^()]*\))?!?: `)
+
+// notImperative reports whether the subject starts with a common verb that
+// isn't in the imperative mood, such as "Added" or "Fixes". It returns the
+// word and its imperative form. Subjects starting with other words aren't
+// judged.
+func notImperative(subject string) (word, base string, ok bool) {
+	subject = conventionalPrefix.ReplaceAllString(subject, "")
+	fields := strings.Fields(subject)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+
+	word = strings.Trim(fields[0], `"'.,:;`)
+	base, ok = inflections[strings.ToLower(word)]
+	if !ok {
+		return "", "", false
+	}
+	// Keep the subject's capitalization in the suggestion.
+	if word != strings.ToLower(word) {
+		base = strings.ToUpper(base[:1]) + base[1:]
+	}
+	return word, base, true
+}
@@ -0,0 +1,264 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/message"
+	"github.com/ivy/git-auto-commit/util/diff"
+)
+
+func TestMessage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Message Suite")
+}
+
+var _ = Describe("ParseConventional", func() {
+	It("parses the header, body, and footers", func() {
+		c, err := message.ParseConventional("feat(config): add a format option\n\n" +
+			"Let users pick Conventional Commits.\n\n" +
+			"Refs: #12\n" +
+			"Closes #34\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Type).To(Equal("feat"))
+		Expect(c.Scope).To(Equal("config"))
+		Expect(c.Breaking).To(BeFalse())
+		Expect(c.Description).To(Equal("add a format option"))
+		Expect(c.Body).To(Equal("Let users pick Conventional Commits."))
+		Expect(c.Footers).To(Equal([]message.Footer{
+			{Token: "Refs", Value: "#12"},
+			{Token: "Closes", Value: "34"},
+		}))
+	})
+
+	It("recognizes breaking changes from the header and the footer", func() {
+		c, err := message.ParseConventional("refactor!: drop Lookup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Breaking).To(BeTrue())
+		Expect(c.Scope).To(BeEmpty())
+
+		c, err = message.ParseConventional("refactor: drop Lookup\n\n" +
+			"BREAKING CHANGE: Lookup was removed;\n" +
+			"use Engine.Lookup instead.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Breaking).To(BeTrue())
+		Expect(c.Body).To(BeEmpty())
+		Expect(c.Footers[0].Value).To(Equal("Lookup was removed;\nuse Engine.Lookup instead."))
+	})
+
+	DescribeTable("rejects messages that don't follow the spec",
+		func(msg, problem string) {
+			_, err := message.ParseConventional(msg)
+			Expect(err).To(MatchError(ContainSubstring(problem)))
+		},
+		Entry("classic style", "Add a format option", "is not in the form"),
+		Entry("missing space", "feat:add a format option", "is not in the form"),
+		Entry("unknown type", "feature: add a format option", `type "feature" is not one of`),
+		Entry("empty description", "feat(config): ", "is not in the form"),
+		Entry("no blank line", "feat: add a format option\nMore details.", "followed by a blank line"),
+		Entry("empty message", "\n\n", "empty"),
+	)
+})
+
+var _ = Describe("Infer", func() {
+	parse := func(text string) []*diff.File {
+		files, err := diff.Parse(text)
+		Expect(err).NotTo(HaveOccurred())
+		return files
+	}
+
+	It("infers the type from the kinds of files changed", func() {
+		hint := message.Infer(parse("diff --git a/README.md b/README.md\n" +
+			"--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-a\n+b\n" +
+			"diff --git a/docs/usage.txt b/docs/usage.txt\n" +
+			"--- a/docs/usage.txt\n+++ b/docs/usage.txt\n@@ -1 +1 @@\n-a\n+b\n"))
+		Expect(hint.Type).To(Equal("docs"))
+		Expect(hint.Scope).To(BeEmpty())
+
+		hint = message.Infer(parse("diff --git a/util/git/git_test.go b/util/git/git_test.go\n" +
+			"--- a/util/git/git_test.go\n+++ b/util/git/git_test.go\n@@ -1 +1 @@\n-a\n+b\n"))
+		Expect(hint.Type).To(Equal("test"))
+		Expect(hint.Scope).To(Equal("util"))
+	})
+
+	It("suggests feat for new source files and leaves mixed changes open", func() {
+		hint := message.Infer(parse("diff --git a/config/format.go b/config/format.go\n" +
+			"new file mode 100644\n--- /dev/null\n+++ b/config/format.go\n@@ -0,0 +1 @@\n+package config\n" +
+			"diff --git a/config/config.go b/config/config.go\n" +
+			"--- a/config/config.go\n+++ b/config/config.go\n@@ -1 +1 @@\n-a\n+b\n"))
+		Expect(hint.Type).To(Equal("feat"))
+		Expect(hint.Scope).To(Equal("config"))
+
+		hint = message.Infer(parse("diff --git a/config/config.go b/config/config.go\n" +
+			"--- a/config/config.go\n+++ b/config/config.go\n@@ -1 +1 @@\n-a\n+b\n" +
+			"diff --git a/go.mod b/go.mod\n" +
+			"--- a/go.mod\n+++ b/go.mod\n@@ -1 +1 @@\n-a\n+b\n"))
+		Expect(hint.Type).To(BeEmpty())
+		Expect(hint.Scope).To(BeEmpty())
+	})
+
+	It("finds exported declarations that were removed and not added back", func() {
+		hint := message.Infer(parse("diff --git a/template/template.go b/template/template.go\n" +
+			"--- a/template/template.go\n+++ b/template/template.go\n" +
+			"@@ -1,6 +1,4 @@\n" +
+			"-func Lookup(name string) (*Template, error) {\n" +
+			"-func (e *Engine) Render(name string) error {\n" +
+			"+func (e *Engine) Render(name string, data any) error {\n" +
+			"-func (e *Engine) lookup(name string) error {\n" +
+			"-type Engine struct {\n" +
+			"+type Engine struct{ mu sync.Mutex }\n" +
+			"-const MaxSize = 10\n" +
+			"diff --git a/cmd/tool/main.go b/cmd/tool/main.go\n" +
+			"--- a/cmd/tool/main.go\n+++ b/cmd/tool/main.go\n" +
+			"@@ -1 +0,0 @@\n" +
+			"-func Run() {\n"))
+		Expect(hint.Removed).To(Equal([]string{"Lookup", "MaxSize"}))
+		Expect(hint.Breaking()).To(BeTrue())
+	})
+
+	It("returns an empty hint for no files", func() {
+		hint := message.Infer(nil)
+		Expect(hint.Type).To(BeEmpty())
+		Expect(hint.Breaking()).To(BeFalse())
+	})
+})
+
+var _ = Describe("Linter", func() {
+	var linter *message.Linter
+
+	BeforeEach(func() {
+		var err error
+		linter, err = message.NewLinter(50, 72, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	rules := func(violations []message.Violation) []string {
+		var names []string
+		for _, v := range violations {
+			names = append(names, v.Rule)
+		}
+		return names
+	}
+
+	It("accepts a well-formed message", func() {
+		Expect(linter.Lint("Add a linter\n\nCheck messages before committing them.")).To(BeEmpty())
+	})
+
+	It("reports each broken rule", func() {
+		msg := "Added a linter that checks every generated commit message\nIt runs " +
+			strings.Repeat("after generation ", 5)
+		Expect(rules(linter.Lint(msg))).To(Equal([]string{
+			message.RuleBlankLine,
+			message.RuleBodyWrap,
+			message.RuleSubjectLength,
+			message.RuleImperative,
+		}))
+
+		Expect(rules(linter.Lint("Fixes the linter."))).To(Equal([]string{
+			message.RuleTrailingPeriod,
+			message.RuleImperative,
+		}))
+		Expect(rules(linter.Lint(`'Add a linter'`))).To(Equal([]string{message.RuleQuotes}))
+		Expect(rules(linter.Lint("```\nAdd a linter\n```"))).To(Equal([]string{message.RuleFences, message.RuleBlankLine}))
+	})
+
+	It("repairs fences, quotes, periods, blank lines, and long body lines", func() {
+		msg := "```\n\"Add a linter.\nIt runs " + strings.Repeat("after generation ", 5) + "\n" +
+			"- and it wraps " + strings.Repeat("list items ", 7) + "\"\n```\n"
+
+		fixed := linter.Fix(msg)
+		Expect(fixed).To(Equal("Add a linter\n\n" +
+			"It runs after generation after generation after generation after\n" +
+			"generation after generation\n" +
+			"- and it wraps list items list items list items list items list items\n" +
+			"  list items list items"))
+		Expect(linter.Lint(fixed)).To(BeEmpty())
+	})
+
+	It("leaves code, URLs, and quotes inside the message alone", func() {
+		msg := "Add \"quoted\" words\n\n\tcode " + strings.Repeat("x", 80) + "\n" +
+			"https://example.com/" + strings.Repeat("x", 80)
+		Expect(linter.Fix(msg)).To(Equal(msg))
+		Expect(linter.Lint(msg)).To(BeEmpty())
+	})
+
+	It("judges the mood of the description in Conventional Commits headers", func() {
+		violations := linter.Lint("feat(config): added a format option")
+		Expect(rules(violations)).To(Equal([]string{message.RuleImperative}))
+		Expect(violations[0].Message).To(ContainSubstring(`("add", not "added")`))
+
+		Expect(linter.Lint("Update docs")).To(BeEmpty())
+		Expect(linter.Lint("Setting up CI")).To(HaveLen(1))
+	})
+
+	It("skips ignored rules", func() {
+		linter, err := message.NewLinter(10, 72, []string{message.RuleSubjectLength, message.RuleImperative})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(linter.Lint("Added a linter with a long subject")).To(BeEmpty())
+
+		linter, err = message.NewLinter(10, 72, []string{"all"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(linter.Fix(`"Added a linter."`)).To(Equal(`"Added a linter."`))
+		Expect(linter.Lint(`"Added a linter."`)).To(BeEmpty())
+	})
+
+	It("rejects unknown rules", func() {
+		_, err := message.NewLinter(50, 72, []string{"spelling"})
+		Expect(err).To(MatchError(ContainSubstring(`unknown lint rule "spelling"`)))
+	})
+})
+
+var _ = Describe("LearnStyle", func() {
+	It("detects shared conventions and picks examples that follow them", func() {
+		style := message.LearnStyle([]string{
+			"parser: handle empty input\n\nFixes ABC-12.",
+			"lexer: add unicode support",
+			"parser: report line numbers in errors\n\nRefs ABC-40.",
+			"docs: describe the grammar\n\nSee ABC-7.",
+			"cli: add a --version flag\n\nCloses ABC-3.",
+			"Bump deps",
+		}, 2)
+
+		Expect(style.Conventions).To(Equal([]string{
+			`Subjects start with the area they change and a colon, as in "parser: handle empty input"`,
+			`Messages reference an issue, such as "ABC-12", in the body`,
+			"Subjects are lowercase",
+			`Subjects use the imperative mood, as in "Add" rather than "Added"`,
+			"Most messages have a body explaining the change",
+			"Subjects are typically about 26 characters long",
+		}))
+		Expect(style.Examples).To(Equal([]string{
+			"parser: handle empty input\n\nFixes ABC-12.",
+			"parser: report line numbers in errors\n\nRefs ABC-40.",
+		}))
+	})
+
+	It("recognizes Conventional Commits, emoji, and the past tense", func() {
+		style := message.LearnStyle([]string{
+			"feat: ✨ Added search",
+			"fix(ui): 🐛 Fixed the layout",
+			"docs: 📝 Updated the README",
+			"feat(api): ✨ Added pagination",
+			"chore: 🔧 Changed the linter config",
+		}, 3)
+
+		Expect(style.Conventions).To(ContainElements(
+			ContainSubstring("Conventional Commits"),
+			"Subjects start with an emoji",
+			"Subjects are capitalized",
+			ContainSubstring(`as in "Added" or "Adds" rather than "Add"`),
+			"Most messages are a single subject line without a body",
+		))
+		Expect(style.NotImperative).To(BeTrue())
+		Expect(style.Examples).To(HaveLen(3))
+	})
+
+	It("learns nothing from a short history", func() {
+		style := message.LearnStyle([]string{"Initial commit", "Add README"}, 3)
+		Expect(style.Conventions).To(BeEmpty())
+		Expect(style.Examples).To(BeEmpty())
+	})
+})
@@ -0,0 +1,288 @@
+package message
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minStyleSamples is the fewest messages a style is learned from. Smaller
+// histories say too little about a team's habits.
+const minStyleSamples = 5
+
+// maxExampleLength is the longest message used as an example, so that a few
+// long messages don't crowd out the rest of the prompt.
+const maxExampleLength = 600
+
+var (
+	// areaPrefix matches subjects that start with the area they change, as in
+	// "net/http: fix a leak".
+	areaPrefix = regexp.MustCompile(`^[\w./-]+: `)
+
+	// bracketPrefix matches subjects that start with a bracketed tag, as in
+	// "[docs] fix a typo".
+	bracketPrefix = regexp.MustCompile(`^\[[^\]]+\] `)
+
+	// emojiCode matches a ":shortcode:" emoji, as used by gitmoji.
+	emojiCode = regexp.MustCompile(`^:[a-z0-9_+-]+: ?`)
+
+	// ticketID matches issue tracker references such as "ABC-123" or "#123".
+	ticketID = regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-\d+\b|(?:^|[\s(])#\d+\b`)
+)
+
+// Style describes the conventions of a repository's commit messages.
+type Style struct {
+	// Conventions describe the habits most messages share, such as "Subjects
+	// are capitalized".
+	Conventions []string
+
+	// Examples are past messages that follow the conventions, newest first.
+	Examples []string
+
+	// NotImperative is set when subjects describe what the commit did, as in
+	// "Added", rather than using the imperative mood that RuleImperative
+	// checks for.
+	NotImperative bool
+}
+
+// features are the conventions a single message follows.
+type features struct {
+	conventional bool
+	areaPrefix   bool
+	bracket      bool
+	emoji        bool
+	ticket       string
+	ticketInBody bool
+	capitalized  bool
+	judged       bool // whether the subject starts with a known verb
+	imperative   bool
+	body         bool
+	length       int
+}
+
+// analyze finds the conventions msg follows.
+func analyze(msg string) features {
+	subject, rest, _ := strings.Cut(msg, "\n")
+	subject = strings.TrimSpace(subject)
+
+	f := features{
+		length: utf8.RuneCountInString(subject),
+		body:   strings.TrimSpace(rest) != "",
+	}
+
+	description := subject
+	if _, err := ParseConventional(subject); err == nil {
+		f.conventional = true
+		description = conventionalPrefix.ReplaceAllString(subject, "")
+	} else if m := areaPrefix.FindString(subject); m != "" {
+		f.areaPrefix = true
+		description = subject[len(m):]
+	} else if m := bracketPrefix.FindString(subject); m != "" {
+		f.bracket = true
+		description = subject[len(m):]
+	}
+
+	if m := emojiCode.FindString(description); m != "" {
+		f.emoji = true
+		description = description[len(m):]
+	} else if r, size := utf8.DecodeRuneInString(description); unicode.Is(unicode.So, r) {
+		f.emoji = true
+		description = strings.TrimSpace(description[size:])
+	}
+
+	if m := ticketID.FindString(subject); m != "" {
+		f.ticket = strings.TrimLeft(m, " \t(")
+	} else if m := ticketID.FindString(rest); m != "" {
+		f.ticket = strings.TrimLeft(m, " \t\n(")
+		f.ticketInBody = true
+	}
+
+	if r, _ := utf8.DecodeRuneInString(description); unicode.IsLetter(r) {
+		f.capitalized = unicode.IsUpper(r)
+	}
+
+	if fields := strings.Fields(description); len(fields) > 0 {
+		word := strings.ToLower(strings.Trim(fields[0], `"'.,:;`))
+		if slices.Contains(verbs, word) || slices.Contains(irregularBases, word) {
+			f.judged, f.imperative = true, true
+		} else if _, ok := inflections[word]; ok {
+			f.judged = true
+		}
+	}
+
+	return f
+}
+
+// irregularBases are the imperative forms in irregularVerbs.
+var irregularBases = func() []string {
+	var bases []string
+	for _, base := range irregularVerbs {
+		if !slices.Contains(bases, base) {
+			bases = append(bases, base)
+		}
+	}
+	return bases
+}()
+
+// LearnStyle finds the conventions shared by most of the given messages, which
+// should be ordered newest first, and picks up to n of the messages that
+// follow them most closely as examples. It returns an empty Style if there are
+// too few messages to learn from.
+func LearnStyle(messages []string, n int) *Style {
+	style := &Style{}
+	if len(messages) < minStyleSamples {
+		return style
+	}
+
+	all := make([]features, len(messages))
+	for i, msg := range messages {
+		all[i] = analyze(msg)
+	}
+	share := func(has func(f features) bool) float64 {
+		count := 0
+		for _, f := range all {
+			if has(f) {
+				count++
+			}
+		}
+		return float64(count) / float64(len(all))
+	}
+
+	// Each convention that most messages follow is described, and kept as a
+	// test for choosing examples.
+	var tests []func(f features) bool
+	add := func(has func(f features) bool, description string, args ...any) {
+		tests = append(tests, has)
+		style.Conventions = append(style.Conventions, fmt.Sprintf(description, args...))
+	}
+
+	const most = 0.6
+	switch {
+	case share(func(f features) bool { return f.conventional }) >= most:
+		add(func(f features) bool { return f.conventional },
+			`Subjects follow Conventional Commits, as in "fix(parser): handle empty input"`)
+	case share(func(f features) bool { return f.areaPrefix }) >= most:
+		add(func(f features) bool { return f.areaPrefix },
+			`Subjects start with the area they change and a colon, as in "parser: handle empty input"`)
+	case share(func(f features) bool { return f.bracket }) >= most:
+		add(func(f features) bool { return f.bracket },
+			`Subjects start with a tag in brackets, as in "[parser] Handle empty input"`)
+	}
+
+	if share(func(f features) bool { return f.emoji }) >= most {
+		add(func(f features) bool { return f.emoji }, "Subjects start with an emoji")
+	}
+
+	if share(func(f features) bool { return f.ticket != "" }) >= 0.4 {
+		inBody := share(func(f features) bool { return f.ticketInBody }) >
+			share(func(f features) bool { return f.ticket != "" && !f.ticketInBody })
+		where := "subject"
+		if inBody {
+			where = "body"
+		}
+		add(func(f features) bool { return f.ticket != "" && f.ticketInBody == inBody },
+			"Messages reference an issue, such as %q, in the %s", mostCommonTicket(all), where)
+	}
+
+	capitalized := share(func(f features) bool { return f.capitalized })
+	if capitalized >= 0.8 {
+		add(func(f features) bool { return f.capitalized }, "Subjects are capitalized")
+	} else if capitalized <= 0.2 {
+		add(func(f features) bool { return !f.capitalized }, "Subjects are lowercase")
+	}
+
+	if judged := share(func(f features) bool { return f.judged }); judged > 0 {
+		imperative := share(func(f features) bool { return f.imperative }) / judged
+		if imperative >= 0.8 {
+			add(func(f features) bool { return !f.judged || f.imperative },
+				`Subjects use the imperative mood, as in "Add" rather than "Added"`)
+		} else if imperative <= 0.2 {
+			style.NotImperative = true
+			add(func(f features) bool { return !f.judged || !f.imperative },
+				`Subjects describe what the commit did, as in "Added" or "Adds" rather than "Add"`)
+		}
+	}
+
+	body := share(func(f features) bool { return f.body })
+	if body >= 0.6 {
+		add(func(f features) bool { return f.body }, "Most messages have a body explaining the change")
+	} else if body <= 0.2 {
+		add(func(f features) bool { return !f.body }, "Most messages are a single subject line without a body")
+	}
+
+	lengths := make([]int, len(all))
+	for i, f := range all {
+		lengths[i] = f.length
+	}
+	slices.Sort(lengths)
+	style.Conventions = append(style.Conventions,
+		fmt.Sprintf("Subjects are typically about %d characters long", lengths[len(lengths)/2]))
+
+	style.Examples = pickExamples(messages, all, tests, n)
+	return style
+}
+
+// mostCommonTicket returns the reference whose project key, or "#" for
+// numbered issues, appears most often.
+func mostCommonTicket(all []features) string {
+	var (
+		counts  = make(map[string]int)
+		example = make(map[string]string)
+		best    string
+	)
+	for _, f := range all {
+		if f.ticket == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(f.ticket, "-")
+		if strings.HasPrefix(f.ticket, "#") {
+			key = "#"
+		}
+		counts[key]++
+		if _, ok := example[key]; !ok {
+			example[key] = f.ticket
+		}
+		if best == "" || counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return example[best]
+}
+
+// pickExamples returns up to n messages that pass the most tests, preferring
+// newer messages among equals. Very long messages are skipped.
+func pickExamples(messages []string, all []features, tests []func(f features) bool, n int) []string {
+	type candidate struct {
+		message string
+		score   int
+	}
+
+	var candidates []candidate
+	for i, msg := range messages {
+		if len(msg) > maxExampleLength || slices.ContainsFunc(candidates, func(c candidate) bool {
+			return c.message == msg
+		}) {
+			continue
+		}
+		score := 0
+		for _, test := range tests {
+			if test(all[i]) {
+				score++
+			}
+		}
+		candidates = append(candidates, candidate{message: msg, score: score})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return b.score - a.score
+	})
+
+	var examples []string
+	for _, c := range candidates[:min(n, len(candidates))] {
+		examples = append(examples, c.message)
+	}
+	return examples
+}
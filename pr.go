@@ -6,9 +6,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
-
 	"github.com/ivy/git-auto-commit/template"
 	"github.com/ivy/git-auto-commit/util/exec"
 	"github.com/ivy/git-auto-commit/util/git"
@@ -27,46 +24,7 @@ func generatePRTitle(
 		return "", err
 	}
 
-	client := openai.NewClient(
-		option.WithAPIKey(cfg.OpenAIAPIKey),
-	)
-
-	stream := client.Chat.Completions.NewStreaming(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			}),
-			Seed:  openai.Int(0),
-			Model: openai.F(openai.ChatModel(cfg.Model)),
-		},
-	)
-
-	acc := openai.ChatCompletionAccumulator{}
-
-	for stream.Next() {
-		chunk := stream.Current()
-		acc.AddChunk(chunk)
-		log.Debugw("stream chunk received", "chunk", chunk)
-
-		// if content, ok := acc.JustFinishedContent(); ok {
-		// 	return content, nil
-		// }
-
-		if refusal, ok := acc.JustFinishedRefusal(); ok {
-			log.Warnw("AI refused to generate commit message",
-				"refusal", refusal)
-			return "", fmt.Errorf("refusal: %s", refusal)
-		}
-	}
-
-	if err := stream.Err(); err != nil {
-		log.Errorw("stream error while generating commit message",
-			"error", err)
-		return "", err
-	}
-
-	return acc.Choices[0].Message.Content, nil
+	return generate(ctx, cfg, "Generating pull request title", prompt)
 }
 
 func generatePRDescription(ctx context.Context, cfg *Config) (string, error) {
@@ -94,46 +52,7 @@ func generatePRDescription(ctx context.Context, cfg *Config) (string, error) {
 	}
 	log.Debugw("pull request prompt", "prompt", prompt)
 
-	client := openai.NewClient(
-		option.WithAPIKey(cfg.OpenAIAPIKey),
-	)
-
-	stream := client.Chat.Completions.NewStreaming(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			}),
-			Seed:  openai.Int(0),
-			Model: openai.F(openai.ChatModel(cfg.Model)),
-		},
-	)
-
-	acc := openai.ChatCompletionAccumulator{}
-
-	for stream.Next() {
-		chunk := stream.Current()
-		acc.AddChunk(chunk)
-		log.Debugw("stream chunk received", "chunk", chunk)
-
-		// if content, ok := acc.JustFinishedContent(); ok {
-		// 	return content, nil
-		// }
-
-		if refusal, ok := acc.JustFinishedRefusal(); ok {
-			log.Warnw("AI refused to generate commit message",
-				"refusal", refusal)
-			return "", fmt.Errorf("refusal: %s", refusal)
-		}
-	}
-
-	if err := stream.Err(); err != nil {
-		log.Errorw("stream error while generating commit message",
-			"error", err)
-		return "", err
-	}
-
-	return acc.Choices[0].Message.Content, nil
+	return generate(ctx, cfg, "Generating pull request description", prompt)
 }
 
 func AutoPullRequest(ctx context.Context, cfg *Config) error {
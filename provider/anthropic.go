@@ -0,0 +1,250 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+const (
+	// anthropicBaseURL is the default endpoint for the Anthropic API.
+	anthropicBaseURL = "https://api.anthropic.com"
+
+	// anthropicVersion is the API version sent in the anthropic-version header.
+	anthropicVersion = "2023-06-01"
+
+	// anthropicMaxTokens caps the length of generated messages. The Messages
+	// API requires an explicit limit.
+	anthropicMaxTokens = 4096
+)
+
+func init() {
+	Register("anthropic", newAnthropic)
+}
+
+// Anthropic is a Completer backed by the Anthropic Messages API.
+type Anthropic struct {
+	// APIKey authenticates requests via the x-api-key header.
+	APIKey string
+
+	// BaseURL overrides the API endpoint. Defaults to https://api.anthropic.com.
+	BaseURL string
+
+	// Headers are extra HTTP headers sent with every request.
+	Headers http.Header
+
+	// HTTPClient sends requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Ensure Anthropic satisfies the Completer interface at compile time.
+var _ Completer = (*Anthropic)(nil)
+
+// newAnthropic is the Factory for the "anthropic" provider.
+func newAnthropic(cfg *config.Config) (Completer, error) {
+	if cfg.AnthropicAPIKey == "" {
+		return nil, errors.New("anthropic API key is not set; use $ANTHROPIC_API_KEY or --anthropic-key")
+	}
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	return &Anthropic{
+		APIKey:  cfg.AnthropicAPIKey,
+		BaseURL: cfg.BaseURL,
+		Headers: headers,
+	}, nil
+}
+
+// anthropicMessage is a single turn in a Messages API request.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicRequest is the body of a Messages API request.
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Stream      bool               `json:"stream"`
+}
+
+// anthropicEvent holds the fields of the streaming events we care about. Each
+// event type populates a different subset of them.
+type anthropicEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage  `json:"usage"`
+	Error *anthropicError `json:"error"`
+}
+
+// anthropicUsage is the token usage reported by message_start, for the input,
+// and message_delta, for the output.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicError is the error object returned by the API.
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *anthropicError) Error() string {
+	return fmt.Sprintf("anthropic: %s: %s", e.Type, e.Message)
+}
+
+// Complete streams a message from the Messages API and returns the accumulated
+// text.
+func (a *Anthropic) Complete(ctx context.Context, req *Request) (*Response, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     req.Model,
+		MaxTokens: anthropicMaxTokens,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: req.Prompt},
+		},
+		Temperature: req.Temperature,
+		Stream:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := a.BaseURL
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost,
+		strings.TrimSuffix(baseURL, "/")+"/v1/messages",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range a.Headers {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("X-Api-Key", a.APIKey)
+	httpReq.Header.Set("Anthropic-Version", anthropicVersion)
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readAnthropicError(resp)
+	}
+
+	var (
+		content    strings.Builder
+		stopReason string
+		usage      Usage
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			// Event names are repeated in the data payload, and blank lines
+			// only delimit events, so everything else can be skipped.
+			continue
+		}
+
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode anthropic event: %w", err)
+		}
+		log.Debugw("stream event received", "event", event)
+
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				content.WriteString(event.Delta.Text)
+				req.emit(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Usage.OutputTokens > 0 {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+		case "error":
+			if event.Error != nil {
+				return nil, event.Error
+			}
+			return nil, errors.New("anthropic: unknown stream error")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Errorw("stream error while generating a response",
+			"error", err)
+		return nil, err
+	}
+
+	switch stopReason {
+	case "refusal":
+		log.Warnw("AI refused to generate a response",
+			"refusal", content.String())
+		return nil, fmt.Errorf("%w: %s", ErrRefusal, content.String())
+	case "max_tokens":
+		log.Warnw("response was truncated at the token limit",
+			"max_tokens", anthropicMaxTokens)
+	case "":
+		return nil, errors.New("anthropic: stream ended before the message was complete")
+	}
+
+	return &Response{
+		Content: content.String(),
+		Usage:   usage,
+	}, nil
+}
+
+// readAnthropicError converts a non-200 response into an error, using the
+// API's error object when the body contains one.
+func readAnthropicError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var payload struct {
+		Error *anthropicError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != nil {
+		return fmt.Errorf("%w (status %d)", payload.Error, resp.StatusCode)
+	}
+	return fmt.Errorf("anthropic: unexpected status %d: %s",
+		resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,172 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+var _ = Describe("Anthropic", func() {
+	var (
+		server    *httptest.Server
+		anthropic *provider.Anthropic
+		request   *http.Request
+		body      map[string]any
+	)
+
+	// serve starts a server that records the request and replies with the
+	// given server-sent events.
+	serve := func(events ...string) {
+		handler := sseHandler(events...)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			body = nil
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			handler(w, r)
+		}))
+		DeferCleanup(server.Close)
+		anthropic = &provider.Anthropic{
+			APIKey:  "test-key",
+			BaseURL: server.URL,
+		}
+	}
+
+	It("is registered under the anthropic name", func() {
+		completer, err := provider.New(&config.Config{
+			Provider:        "anthropic",
+			AnthropicAPIKey: "test-key",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completer).To(BeAssignableToTypeOf(&provider.Anthropic{}))
+	})
+
+	It("sends requests to the configured base URL", func() {
+		serve(
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Fix typo\"}}",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		)
+
+		completer, err := provider.New(&config.Config{
+			Provider:        "anthropic",
+			AnthropicAPIKey: "test-key",
+			BaseURL:         server.URL + "/gateway",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := completer.Complete(context.Background(), &provider.Request{Model: "claude-test", Prompt: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+		Expect(request.URL.Path).To(Equal("/gateway/v1/messages"))
+	})
+
+	It("requires an API key", func() {
+		_, err := provider.New(&config.Config{Provider: "anthropic"})
+		Expect(err).To(MatchError(ContainSubstring("ANTHROPIC_API_KEY")))
+	})
+
+	It("accumulates streamed text deltas", func() {
+		serve(
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}",
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+			"event: ping\ndata: {\"type\":\"ping\"}",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Fix \"}}",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"typo\"}}",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		)
+
+		var tokens []string
+		resp, err := anthropic.Complete(context.Background(), &provider.Request{
+			Model:   "claude-test",
+			Prompt:  "hello",
+			OnToken: func(token string) { tokens = append(tokens, token) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+		Expect(resp.Usage).To(Equal(provider.Usage{InputTokens: 12, OutputTokens: 2}))
+		Expect(tokens).To(Equal([]string{"Fix ", "typo"}))
+
+		Expect(request.URL.Path).To(Equal("/v1/messages"))
+		Expect(request.Header.Get("X-Api-Key")).To(Equal("test-key"))
+		Expect(request.Header.Get("Anthropic-Version")).NotTo(BeEmpty())
+		Expect(body).To(HaveKeyWithValue("model", "claude-test"))
+		Expect(body).To(HaveKeyWithValue("stream", true))
+	})
+
+	It("sends the temperature only when set", func() {
+		events := []string{
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Fix typo\"}}",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}",
+		}
+
+		serve(events...)
+		_, err := anthropic.Complete(context.Background(), &provider.Request{
+			Model:  "claude-test",
+			Prompt: "hello",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).NotTo(HaveKey("temperature"))
+
+		serve(events...)
+		temperature := 0.7
+		_, err = anthropic.Complete(context.Background(), &provider.Request{
+			Model:       "claude-test",
+			Prompt:      "hello",
+			Temperature: &temperature,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(HaveKeyWithValue("temperature", 0.7))
+	})
+
+	It("returns ErrRefusal when the model refuses", func() {
+		serve(
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"I can't\"}}",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"refusal\"}}",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		)
+
+		resp, err := anthropic.Complete(context.Background(), &provider.Request{
+			Model:  "claude-test",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(provider.ErrRefusal))
+		Expect(err).To(MatchError(ContainSubstring("I can't")))
+		Expect(resp).To(BeNil())
+	})
+
+	It("returns stream errors", func() {
+		serve(
+			"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}",
+		)
+
+		_, err := anthropic.Complete(context.Background(), &provider.Request{
+			Model:  "claude-test",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(ContainSubstring("overloaded_error: Overloaded")))
+	})
+
+	It("returns API errors for non-200 responses", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+		}))
+		DeferCleanup(server.Close)
+		anthropic = &provider.Anthropic{APIKey: "bad", BaseURL: server.URL}
+
+		_, err := anthropic.Complete(context.Background(), &provider.Request{
+			Model:  "claude-test",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(ContainSubstring("authentication_error: invalid x-api-key (status 401)")))
+	})
+})
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/option"
+
+	"github.com/ivy/git-auto-commit/config"
+)
+
+func init() {
+	Register("azure", newAzure)
+}
+
+// newAzure is the Factory for the "azure" provider. Azure OpenAI serves the
+// Chat Completions API under a per-deployment path, versioned by the
+// api-version query parameter and authenticated with an api-key header, so it
+// reuses the OpenAI Completer with those options applied.
+func newAzure(cfg *config.Config) (Completer, error) {
+	if cfg.AzureEndpoint == "" {
+		return nil, errors.New("azure endpoint is not set; use $AZURE_OPENAI_ENDPOINT or `git config auto-commit.azure-endpoint`")
+	}
+	if cfg.AzureAPIKey == "" {
+		return nil, errors.New("azure API key is not set; use $AZURE_OPENAI_API_KEY or --azure-key")
+	}
+
+	deployment := cfg.AzureDeployment
+	if deployment == "" {
+		deployment = cfg.Model
+	}
+
+	opts, err := openAIOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts,
+		option.WithBaseURL(azureBaseURL(cfg.AzureEndpoint, deployment)),
+		option.WithQuery("api-version", cfg.AzureAPIVersion),
+		option.WithHeader("api-key", cfg.AzureAPIKey),
+		// NewClient reads OPENAI_API_KEY into a bearer token by default,
+		// which Azure would reject in favor of the api-key header.
+		option.WithHeaderDel("authorization"),
+	)
+	return NewOpenAI(opts...), nil
+}
+
+// azureBaseURL returns the base URL for a deployment on an Azure OpenAI
+// resource, for example
+// "https://example.openai.azure.com/openai/deployments/gpt-4o-mini/".
+func azureBaseURL(endpoint, deployment string) string {
+	return strings.TrimSuffix(endpoint, "/") +
+		"/openai/deployments/" + url.PathEscape(deployment) + "/"
+}
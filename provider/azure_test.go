@@ -0,0 +1,83 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+var _ = Describe("Azure", func() {
+	var (
+		server  *httptest.Server
+		request *http.Request
+	)
+
+	BeforeEach(func() {
+		handler := sseHandler(
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Fix typo"},"finish_reason":"stop"}]}`,
+			`data: [DONE]`,
+		)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			handler(w, r)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("sends requests to the deployment with api-version and api-key", func() {
+		completer, err := provider.New(&config.Config{
+			Provider:        "azure",
+			Model:           "gpt-4o-mini",
+			OpenAIAPIKey:    "openai-key",
+			AzureEndpoint:   server.URL,
+			AzureDeployment: "team-gpt",
+			AzureAPIVersion: "2024-10-21",
+			AzureAPIKey:     "azure-key",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := completer.Complete(context.Background(), &provider.Request{
+			Model:  "gpt-4o-mini",
+			Prompt: "hello",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+
+		Expect(request.URL.Path).To(Equal("/openai/deployments/team-gpt/chat/completions"))
+		Expect(request.URL.Query().Get("api-version")).To(Equal("2024-10-21"))
+		Expect(request.Header.Get("Api-Key")).To(Equal("azure-key"))
+		Expect(request.Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("uses the model as the deployment name by default", func() {
+		completer, err := provider.New(&config.Config{
+			Provider:        "azure",
+			Model:           "gpt-4o",
+			AzureEndpoint:   server.URL + "/",
+			AzureAPIVersion: "2024-10-21",
+			AzureAPIKey:     "azure-key",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = completer.Complete(context.Background(), &provider.Request{
+			Model:  "gpt-4o",
+			Prompt: "hello",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.URL.Path).To(Equal("/openai/deployments/gpt-4o/chat/completions"))
+	})
+
+	It("requires an endpoint and an API key", func() {
+		_, err := provider.New(&config.Config{Provider: "azure", AzureAPIKey: "azure-key"})
+		Expect(err).To(MatchError(ContainSubstring("AZURE_OPENAI_ENDPOINT")))
+
+		_, err = provider.New(&config.Config{Provider: "azure", AzureEndpoint: server.URL})
+		Expect(err).To(MatchError(ContainSubstring("AZURE_OPENAI_API_KEY")))
+	})
+})
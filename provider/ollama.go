@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// ollamaHost is the default address of a local Ollama server.
+const ollamaHost = "http://localhost:11434"
+
+func init() {
+	Register("ollama", newOllama)
+}
+
+// Ollama is a Completer backed by a local Ollama server. It needs no API key.
+type Ollama struct {
+	// Host is the address of the Ollama server, for example
+	// "http://localhost:11434". A missing scheme defaults to http.
+	Host string
+
+	// Headers are extra HTTP headers sent with every request.
+	Headers http.Header
+
+	// HTTPClient sends requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Ensure Ollama satisfies the Completer interface at compile time.
+var _ Completer = (*Ollama)(nil)
+
+// newOllama is the Factory for the "ollama" provider.
+func newOllama(cfg *config.Config) (Completer, error) {
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	return &Ollama{
+		Host:    cfg.OllamaHost,
+		Headers: headers,
+	}, nil
+}
+
+// ollamaMessage is a single turn in a chat request or response.
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaRequest is the body of an /api/chat request.
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaChunk is a single line of a streamed /api/chat response.
+type ollamaChunk struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error"`
+
+	// PromptEvalCount and EvalCount are the input and output token counts,
+	// sent with the final chunk.
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// baseURL returns the server address with a scheme and without a trailing
+// slash. OLLAMA_HOST is commonly set to a bare "host:port".
+func (o *Ollama) baseURL() string {
+	host := o.Host
+	if host == "" {
+		host = ollamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// Complete streams a chat response from Ollama and returns the accumulated
+// content.
+func (o *Ollama) Complete(ctx context.Context, req *Request) (*Response, error) {
+	options := map[string]any{
+		"seed": 0,
+	}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+
+	body, err := json.Marshal(ollamaRequest{
+		Model: req.Model,
+		Messages: []ollamaMessage{
+			{Role: "user", Content: req.Prompt},
+		},
+		Stream:  true,
+		Options: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, o.baseURL()+"/api/chat", bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range o.Headers {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama at %s: %w", o.baseURL(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, readOllamaError(resp)
+	}
+
+	var (
+		content strings.Builder
+		done    bool
+		usage   Usage
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+		}
+		log.Debugw("stream chunk received", "chunk", chunk)
+
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+
+		content.WriteString(chunk.Message.Content)
+		req.emit(chunk.Message.Content)
+
+		if chunk.Done {
+			if chunk.DoneReason == "length" {
+				log.Warnw("response was truncated at the token limit")
+			}
+			usage = Usage{
+				InputTokens:  chunk.PromptEvalCount,
+				OutputTokens: chunk.EvalCount,
+			}
+			done = true
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Errorw("stream error while generating a response",
+			"error", err)
+		return nil, err
+	}
+
+	if !done {
+		return nil, errors.New("ollama: stream ended before the response was complete")
+	}
+
+	return &Response{
+		Content: content.String(),
+		Usage:   usage,
+	}, nil
+}
+
+// readOllamaError converts a non-200 response into an error, using the
+// server's error message when the body contains one.
+func readOllamaError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		return fmt.Errorf("ollama: %s (status %d)", payload.Error, resp.StatusCode)
+	}
+	return fmt.Errorf("ollama: unexpected status %d: %s",
+		resp.StatusCode, strings.TrimSpace(string(body)))
+}
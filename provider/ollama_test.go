@@ -0,0 +1,124 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+var _ = Describe("Ollama", func() {
+	var (
+		server  *httptest.Server
+		ollama  *provider.Ollama
+		request *http.Request
+		body    map[string]any
+	)
+
+	// serve starts a stub Ollama server that records the request and replies
+	// with the given status and newline-delimited JSON lines.
+	serve := func(status int, lines ...string) {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			body = nil
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(status)
+			for _, line := range lines {
+				fmt.Fprintln(w, line)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		ollama = &provider.Ollama{Host: server.URL}
+	}
+
+	It("is registered under the ollama name", func() {
+		completer, err := provider.New(&config.Config{Provider: "ollama"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completer).To(BeAssignableToTypeOf(&provider.Ollama{}))
+	})
+
+	It("accumulates streamed content without an API key", func() {
+		serve(http.StatusOK,
+			`{"model":"llama3.2","message":{"role":"assistant","content":"Fix "},"done":false}`,
+			`{"model":"llama3.2","message":{"role":"assistant","content":"typo"},"done":false}`,
+			`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":2}`,
+		)
+
+		var tokens []string
+		resp, err := ollama.Complete(context.Background(), &provider.Request{
+			Model:   "llama3.2",
+			Prompt:  "hello",
+			OnToken: func(token string) { tokens = append(tokens, token) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+		Expect(resp.Usage).To(Equal(provider.Usage{InputTokens: 12, OutputTokens: 2}))
+		Expect(tokens).To(Equal([]string{"Fix ", "typo"}))
+
+		Expect(request.URL.Path).To(Equal("/api/chat"))
+		Expect(request.Header.Get("Authorization")).To(BeEmpty())
+		Expect(body).To(HaveKeyWithValue("model", "llama3.2"))
+		Expect(body).To(HaveKeyWithValue("stream", true))
+	})
+
+	It("passes the temperature as a model option", func() {
+		serve(http.StatusOK,
+			`{"message":{"role":"assistant","content":"Fix typo"},"done":true}`,
+		)
+
+		temperature := 0.7
+		_, err := ollama.Complete(context.Background(), &provider.Request{
+			Model:       "llama3.2",
+			Prompt:      "hello",
+			Temperature: &temperature,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(HaveKeyWithValue("options", HaveKeyWithValue("temperature", 0.7)))
+	})
+
+	It("accepts a host without a scheme", func() {
+		serve(http.StatusOK,
+			`{"message":{"role":"assistant","content":"Fix typo"},"done":true}`,
+		)
+		ollama.Host = strings.TrimPrefix(server.URL, "http://")
+
+		resp, err := ollama.Complete(context.Background(), &provider.Request{
+			Model:  "llama3.2",
+			Prompt: "hello",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+	})
+
+	It("returns errors reported by the server", func() {
+		serve(http.StatusNotFound, `{"error":"model \"missing\" not found"}`)
+
+		_, err := ollama.Complete(context.Background(), &provider.Request{
+			Model:  "missing",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(ContainSubstring(`model "missing" not found (status 404)`)))
+	})
+
+	It("returns an error if the stream ends early", func() {
+		serve(http.StatusOK,
+			`{"message":{"role":"assistant","content":"Fix "},"done":false}`,
+		)
+
+		_, err := ollama.Complete(context.Background(), &provider.Request{
+			Model:  "llama3.2",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(ContainSubstring("stream ended")))
+	})
+})
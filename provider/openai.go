@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+func init() {
+	Register("openai", newOpenAI)
+}
+
+// OpenAI is a Completer backed by the OpenAI Chat Completions API.
+type OpenAI struct {
+	client *openai.Client
+}
+
+// Ensure OpenAI satisfies the Completer interface at compile time.
+var _ Completer = (*OpenAI)(nil)
+
+// NewOpenAI returns an OpenAI Completer configured with the given client
+// options.
+func NewOpenAI(opts ...option.RequestOption) *OpenAI {
+	return &OpenAI{
+		client: openai.NewClient(opts...),
+	}
+}
+
+// newOpenAI is the Factory for the "openai" provider.
+func newOpenAI(cfg *config.Config) (Completer, error) {
+	opts, err := openAIOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, option.WithAPIKey(cfg.OpenAIAPIKey))
+	return NewOpenAI(opts...), nil
+}
+
+// openAIOptions returns the client options shared by every OpenAI-compatible
+// provider: the base URL, organization, project, and extra headers.
+func openAIOptions(cfg *config.Config) ([]option.RequestOption, error) {
+	var opts []option.RequestOption
+
+	if cfg.BaseURL != "" {
+		// Paths are resolved relative to the base URL, so it must end in a
+		// slash to keep prefixes such as "/v1".
+		baseURL := cfg.BaseURL
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		opts = append(opts, option.WithBaseURL(baseURL))
+	}
+	if cfg.Organization != "" {
+		opts = append(opts, option.WithOrganization(cfg.Organization))
+	}
+	if cfg.Project != "" {
+		opts = append(opts, option.WithProject(cfg.Project))
+	}
+
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			opts = append(opts, option.WithHeaderAdd(name, value))
+		}
+	}
+
+	return opts, nil
+}
+
+// Complete streams a chat completion and returns the accumulated content.
+func (o *OpenAI) Complete(ctx context.Context, req *Request) (*Response, error) {
+	params := openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(req.Prompt),
+		}),
+		Seed:  openai.Int(0),
+		Model: openai.F(openai.ChatModel(req.Model)),
+		StreamOptions: openai.F(openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.F(true),
+		}),
+	}
+	if req.Temperature != nil {
+		params.Temperature = openai.Float(*req.Temperature)
+	}
+
+	stream := o.client.Chat.Completions.NewStreaming(ctx, params)
+
+	acc := openai.ChatCompletionAccumulator{}
+
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		log.Debugw("stream chunk received", "chunk", chunk)
+
+		if len(chunk.Choices) > 0 {
+			req.emit(chunk.Choices[0].Delta.Content)
+		}
+
+		if refusal, ok := acc.JustFinishedRefusal(); ok {
+			log.Warnw("AI refused to generate a response",
+				"refusal", refusal)
+			return nil, fmt.Errorf("%w: %s", ErrRefusal, refusal)
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		log.Errorw("stream error while generating a response",
+			"error", err)
+		return nil, err
+	}
+
+	if len(acc.Choices) == 0 {
+		return nil, errors.New("no choices returned by the model")
+	}
+
+	return &Response{
+		Content: acc.Choices[0].Message.Content,
+		Usage: Usage{
+			InputTokens:  int(acc.Usage.PromptTokens),
+			OutputTokens: int(acc.Usage.CompletionTokens),
+		},
+	}, nil
+}
@@ -0,0 +1,127 @@
+package provider_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openai/openai-go/option"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+// sseHandler returns an http.HandlerFunc that replies with the given
+// server-sent events.
+func sseHandler(events ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "%s\n\n", event)
+		}
+	}
+}
+
+var _ = Describe("OpenAI", func() {
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newOpenAI := func() *provider.OpenAI {
+		return provider.NewOpenAI(
+			option.WithBaseURL(server.URL),
+			option.WithAPIKey("test-key"),
+			option.WithMaxRetries(0),
+		)
+	}
+
+	It("accumulates streamed content", func() {
+		server = httptest.NewServer(sseHandler(
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Fix "}}]}`,
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"typo"},"finish_reason":"stop"}]}`,
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2,"total_tokens":14}}`,
+			`data: [DONE]`,
+		))
+
+		var tokens []string
+		resp, err := newOpenAI().Complete(context.Background(), &provider.Request{
+			Model:   "gpt-4o-mini",
+			Prompt:  "hello",
+			OnToken: func(token string) { tokens = append(tokens, token) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Fix typo"))
+		Expect(resp.Usage).To(Equal(provider.Usage{InputTokens: 12, OutputTokens: 2}))
+		Expect(tokens).To(Equal([]string{"Fix ", "typo"}))
+	})
+
+	It("returns ErrRefusal when the model refuses", func() {
+		server = httptest.NewServer(sseHandler(
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","refusal":"I can't"}}]}`,
+			`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			`data: [DONE]`,
+		))
+
+		resp, err := newOpenAI().Complete(context.Background(), &provider.Request{
+			Model:  "gpt-4o-mini",
+			Prompt: "hello",
+		})
+		Expect(err).To(MatchError(provider.ErrRefusal))
+		Expect(err).To(MatchError(ContainSubstring("I can't")))
+		Expect(resp).To(BeNil())
+	})
+
+	Context("when built from config", func() {
+		var request *http.Request
+
+		BeforeEach(func() {
+			handler := sseHandler(
+				`data: {"id":"1","object":"chat.completion.chunk","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Fix typo"},"finish_reason":"stop"}]}`,
+				`data: [DONE]`,
+			)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				handler(w, r)
+			}))
+		})
+
+		It("sends requests to the base URL with extra headers and IDs", func() {
+			completer, err := provider.New(&config.Config{
+				Provider:     "openai",
+				OpenAIAPIKey: "test-key",
+				BaseURL:      server.URL + "/gateway/v1",
+				Headers:      []string{"X-Team: platform", "X-Trace: abc"},
+				Organization: "org-123",
+				Project:      "proj-456",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resp, err := completer.Complete(context.Background(), &provider.Request{
+				Model:  "gpt-4o-mini",
+				Prompt: "hello",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Content).To(Equal("Fix typo"))
+
+			Expect(request.URL.Path).To(Equal("/gateway/v1/chat/completions"))
+			Expect(request.Header.Get("Authorization")).To(Equal("Bearer test-key"))
+			Expect(request.Header.Get("X-Team")).To(Equal("platform"))
+			Expect(request.Header.Get("X-Trace")).To(Equal("abc"))
+			Expect(request.Header.Get("OpenAI-Organization")).To(Equal("org-123"))
+			Expect(request.Header.Get("OpenAI-Project")).To(Equal("proj-456"))
+		})
+
+		It("rejects malformed headers", func() {
+			_, err := provider.New(&config.Config{
+				Provider: "openai",
+				Headers:  []string{"no-colon"},
+			})
+			Expect(err).To(MatchError(ContainSubstring(`invalid header "no-colon"`)))
+		})
+	})
+})
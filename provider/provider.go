@@ -0,0 +1,134 @@
+// Package provider abstracts the LLM backends used to generate commit and pull
+// request messages. Each backend implements Completer and registers a Factory
+// under the name users select with `auto-commit.provider` or --provider.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ivy/git-auto-commit/config"
+)
+
+// ErrRefusal is returned, wrapped with the model's explanation, when the model
+// refuses to complete a request.
+var ErrRefusal = errors.New("refusal")
+
+// Request is a single prompt sent to a Completer.
+type Request struct {
+	// Model is the name of the model to use, for example "gpt-4o-mini".
+	Model string
+
+	// Prompt is the user message sent to the model.
+	Prompt string
+
+	// Temperature, if set, overrides the provider's default sampling
+	// temperature. Higher values produce more varied responses.
+	Temperature *float64
+
+	// OnToken, if set, is called with each piece of text as it streams in.
+	OnToken func(token string)
+}
+
+// emit passes a streamed token to the request's OnToken callback, if any.
+func (r *Request) emit(token string) {
+	if r.OnToken != nil && token != "" {
+		r.OnToken(token)
+	}
+}
+
+// Response is the result of a completed Request.
+type Response struct {
+	// Content is the full text generated by the model.
+	Content string
+
+	// Usage counts the tokens the request used, as reported by the provider.
+	// It's zero if the provider doesn't report usage.
+	Usage Usage
+}
+
+// Usage counts the tokens used by a request.
+type Usage struct {
+	// InputTokens is the number of tokens in the prompt.
+	InputTokens int
+
+	// OutputTokens is the number of tokens generated.
+	OutputTokens int
+}
+
+// Completer is implemented by each LLM backend.
+type Completer interface {
+	// Complete sends the request to the model and returns its full response.
+	Complete(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Factory constructs a Completer from the user's configuration.
+type Factory func(cfg *config.Config) (Completer, error)
+
+var (
+	mu sync.RWMutex
+
+	// factories maps provider names to their constructors.
+	factories = make(map[string]Factory)
+)
+
+// Register makes a provider available under the given name. Registering a name
+// twice replaces the earlier factory, which lets tests inject fakes.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// Unregister removes the provider registered under the given name.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(factories, name)
+}
+
+// Names returns the sorted names of all registered providers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a Completer for the provider named by cfg.Provider. It returns an
+// error if no such provider has been registered.
+func New(cfg *config.Config) (Completer, error) {
+	mu.RLock()
+	factory, ok := factories[cfg.Provider]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %v)", cfg.Provider, Names())
+	}
+	return factory(cfg)
+}
+
+// parseHeaders parses headers in "Name: value" form, as stored in
+// config.Config.Headers.
+func parseHeaders(raw []string) (http.Header, error) {
+	headers := make(http.Header, len(raw))
+	for _, line := range raw {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
@@ -0,0 +1,68 @@
+package provider_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/provider"
+)
+
+func TestProvider(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Provider Suite")
+}
+
+// fakeCompleter is a Completer that returns a canned response and records the
+// requests it receives.
+type fakeCompleter struct {
+	requests []*provider.Request
+	content  string
+}
+
+func (f *fakeCompleter) Complete(_ context.Context, req *provider.Request) (*provider.Response, error) {
+	f.requests = append(f.requests, req)
+	return &provider.Response{Content: f.content}, nil
+}
+
+var _ = Describe("Registry", func() {
+	AfterEach(func() {
+		provider.Unregister("fake")
+	})
+
+	It("registers the built-in openai provider", func() {
+		Expect(provider.Names()).To(ContainElement("openai"))
+
+		completer, err := provider.New(&config.Config{Provider: "openai"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completer).To(BeAssignableToTypeOf(&provider.OpenAI{}))
+	})
+
+	It("returns the Completer built by a registered factory", func() {
+		fake := &fakeCompleter{content: "Add feature"}
+		provider.Register("fake", func(cfg *config.Config) (provider.Completer, error) {
+			return fake, nil
+		})
+
+		completer, err := provider.New(&config.Config{Provider: "fake"})
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := completer.Complete(context.Background(), &provider.Request{
+			Model:  "fake-model",
+			Prompt: "hello",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Content).To(Equal("Add feature"))
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Prompt).To(Equal("hello"))
+	})
+
+	It("returns an error for an unknown provider", func() {
+		completer, err := provider.New(&config.Config{Provider: "nonexistent"})
+		Expect(err).To(MatchError(ContainSubstring(`unknown provider "nonexistent"`)))
+		Expect(completer).To(BeNil())
+	})
+})
@@ -0,0 +1,305 @@
+// Package redact finds likely secrets, such as API keys and private keys, in
+// diffs and masks them before the diffs are sent to a provider.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Detector finds one kind of secret in a line of text.
+type Detector struct {
+	// Name identifies the detector in findings and masks.
+	Name string
+
+	// Pattern matches the secret. If it has a capturing group, only the
+	// first group is masked; otherwise, the whole match is.
+	Pattern *regexp.Regexp
+
+	// Check, if set, must also accept the masked text for it to count as a
+	// secret.
+	Check func(secret string) bool
+}
+
+// Finding is a likely secret found in a diff.
+type Finding struct {
+	// Detector is the name of the detector that found the secret.
+	Detector string
+
+	// File is the path of the file the secret is in, or empty if the input
+	// wasn't a diff.
+	File string
+
+	// Line is the secret's line number in the file: in the new version of the
+	// file for added and unchanged lines, and in the old version for removed
+	// ones.
+	Line int
+}
+
+func (f Finding) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("line %d (%s)", f.Line, f.Detector)
+	}
+	return fmt.Sprintf("%s:%d (%s)", f.File, f.Line, f.Detector)
+}
+
+// Detectors are the built-in detectors.
+var Detectors = []Detector{
+	{
+		Name:    "aws-access-key-id",
+		Pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+	},
+	{
+		Name:    "aws-secret-access-key",
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		Name:    "github-token",
+		Pattern: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`),
+	},
+	{
+		Name: "high-entropy-string",
+		// Quoted strings and assigned values, as found in source and .env
+		// files. A colon must be followed by a space, so that values such as
+		// go.sum's "h1:" hashes aren't mistaken for assignments.
+		Pattern: regexp.MustCompile(`(?:["'\x60]|=\s*|:\s+)([A-Za-z0-9+/=_\-]{24,})`),
+		Check:   isHighEntropy,
+	},
+}
+
+// privateKeyBegin and privateKeyEnd delimit a PEM-encoded private key.
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+)
+
+// privateKeyDetector is the name reported for private key blocks, which span
+// several lines and so aren't matched by a Detector.
+const privateKeyDetector = "private-key"
+
+// hunkHeader matches the start lines in a hunk's "@@" line.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// Redactor masks secrets found by its detectors.
+type Redactor struct {
+	detectors []Detector
+}
+
+// New returns a Redactor that uses the built-in Detectors plus one detector
+// for each of the given regular expressions. It returns an error if any of
+// them fail to compile.
+func New(patterns []string) (*Redactor, error) {
+	detectors := append([]Detector{}, Detectors...)
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", pattern, err)
+		}
+		detectors = append(detectors, Detector{
+			Name:    "pattern-" + strconv.Itoa(i+1),
+			Pattern: re,
+		})
+	}
+	return &Redactor{detectors: detectors}, nil
+}
+
+// mask is the text that replaces a secret found by the named detector.
+func mask(detector string) string {
+	return "[REDACTED " + detector + "]"
+}
+
+// Redact returns the text with every likely secret masked, and where each one
+// was found. The text is usually a diff from `git diff`; secrets are then
+// located by file and line, and diff headers are left alone. Other text is
+// scanned line by line.
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	var (
+		b        strings.Builder
+		findings []Finding
+
+		file             string
+		inHunk, inKey    bool
+		oldLine, newLine int
+		plainLine        int
+	)
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+
+		// Track where we are in the diff. Header lines are copied as is.
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file, inHunk, inKey = "", false, false
+			b.WriteString(line)
+			continue
+		case !inHunk && strings.HasPrefix(line, "--- "):
+			file = diffPath(line[4:])
+			b.WriteString(line)
+			continue
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			if path := diffPath(line[4:]); path != "/dev/null" {
+				file = path
+			}
+			b.WriteString(line)
+			continue
+		case strings.HasPrefix(line, "@@"):
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				oldLine, _ = strconv.Atoi(m[1])
+				newLine, _ = strconv.Atoi(m[2])
+				inHunk = true
+			}
+			b.WriteString(line)
+			continue
+		}
+
+		// Split the diff marker from the content, and work out the line
+		// number the content is at.
+		prefix, content, lineNo := "", line, 0
+		if inHunk && strings.ContainsRune(" +-", rune(line[0])) {
+			prefix, content = line[:1], line[1:]
+			switch prefix {
+			case "-":
+				lineNo = oldLine
+				oldLine++
+			case "+":
+				lineNo = newLine
+				newLine++
+			default:
+				lineNo = newLine
+				oldLine++
+				newLine++
+			}
+		} else if inHunk {
+			// "\ No newline at end of file" and the like.
+			b.WriteString(line)
+			continue
+		} else {
+			plainLine++
+			lineNo = plainLine
+		}
+
+		b.WriteString(prefix)
+
+		// Private keys span several lines, so everything from the BEGIN line
+		// to the END line is masked.
+		if !inKey && privateKeyBegin.MatchString(content) {
+			inKey = true
+			findings = append(findings, Finding{Detector: privateKeyDetector, File: file, Line: lineNo})
+		}
+		if inKey {
+			if privateKeyEnd.MatchString(content) {
+				inKey = false
+			}
+			b.WriteString(mask(privateKeyDetector))
+			if strings.HasSuffix(content, "\n") {
+				b.WriteString("\n")
+			}
+			continue
+		}
+
+		redacted, found := r.redactLine(content)
+		for _, name := range found {
+			findings = append(findings, Finding{Detector: name, File: file, Line: lineNo})
+		}
+		b.WriteString(redacted)
+	}
+
+	return b.String(), findings
+}
+
+// redactLine masks the secrets in a single line, returning the masked line
+// and the names of the detectors that matched.
+func (r *Redactor) redactLine(line string) (string, []string) {
+	var found []string
+	for _, d := range r.detectors {
+		matched := false
+		line = replaceSecrets(d, line, func(secret string) string {
+			if d.Check != nil && !d.Check(secret) {
+				return secret
+			}
+			matched = true
+			return mask(d.Name)
+		})
+		if matched {
+			found = append(found, d.Name)
+		}
+	}
+	return line, found
+}
+
+// replaceSecrets replaces each secret matched by the detector in line with
+// the result of repl.
+func replaceSecrets(d Detector, line string, repl func(string) string) string {
+	matches := d.Pattern.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		b.WriteString(line[last:start])
+		b.WriteString(repl(line[start:end]))
+		last = end
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// diffPath returns the path named in a "---" or "+++" line, without its
+// "a/" or "b/" prefix.
+func diffPath(s string) string {
+	s = strings.TrimRight(s, "\n")
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	s, _, _ = strings.Cut(s, "\t")
+	if p, ok := strings.CutPrefix(s, "a/"); ok {
+		return p
+	}
+	if p, ok := strings.CutPrefix(s, "b/"); ok {
+		return p
+	}
+	return s
+}
+
+// isHighEntropy reports whether s looks like a random token rather than a
+// word or identifier: it mixes letters and digits and has a high Shannon
+// entropy per character.
+func isHighEntropy(s string) bool {
+	var letters, digits bool
+	for _, r := range s {
+		letters = letters || unicode.IsLetter(r)
+		digits = digits || unicode.IsDigit(r)
+	}
+	if !letters || !digits {
+		return false
+	}
+	return entropy(s) >= 4.0
+}
+
+// entropy returns the Shannon entropy of s, in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	n := float64(len(s))
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
@@ -0,0 +1,132 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/util/term"
+)
+
+// ErrAborted is returned when the user quits instead of committing.
+var ErrAborted = errors.New("aborted by user")
+
+// reviewChoice is the user's answer to the review prompt.
+type reviewChoice int
+
+const (
+	// choiceAccept commits the message as generated.
+	choiceAccept reviewChoice = iota
+	// choiceEdit opens the message in the editor before committing.
+	choiceEdit
+	// choiceRegenerate asks the model for a new message.
+	choiceRegenerate
+	// choiceQuit aborts without committing.
+	choiceQuit
+)
+
+// reviewMessage shows the message on w and asks the user what to do with it,
+// reading answers from r until it gets a valid one. An empty answer accepts
+// the message. The message is shown as it will be committed, since linting may
+// have changed it since it was streamed.
+func reviewMessage(r *bufio.Reader, w io.Writer, message string) (reviewChoice, error) {
+	fmt.Fprintln(w, "Commit message:")
+	for _, line := range strings.Split(message, "\n") {
+		fmt.Fprintln(w, strings.TrimRight("    "+line, " "))
+	}
+	fmt.Fprintln(w)
+
+	for {
+		answer, err := term.Prompt(r, w,
+			"Commit with this message? [A]ccept, [e]dit, [r]egenerate, [q]uit: ")
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// Treat a closed stdin (e.g. ^D) like quitting.
+				fmt.Fprintln(w)
+				return choiceQuit, nil
+			}
+			return choiceQuit, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "", "a", "accept", "y", "yes":
+			return choiceAccept, nil
+		case "e", "edit":
+			return choiceEdit, nil
+		case "r", "regenerate":
+			return choiceRegenerate, nil
+		case "q", "quit", "n", "no":
+			return choiceQuit, nil
+		}
+
+		fmt.Fprintf(w, "Unknown answer %q.\n", answer)
+	}
+}
+
+// pickCandidate shows a numbered list of candidate messages on w and asks the
+// user to pick one, returning its index. It returns ErrAborted if the user
+// quits.
+func pickCandidate(r *bufio.Reader, w io.Writer, candidates []string) (int, error) {
+	for i, candidate := range candidates {
+		lines := strings.Split(strings.TrimSpace(candidate), "\n")
+		fmt.Fprintf(w, "[%d] %s\n", i+1, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+		fmt.Fprintln(w)
+	}
+
+	question := fmt.Sprintf("Pick a message [1-%d], or [q]uit: ", len(candidates))
+	for {
+		answer, err := term.Prompt(r, w, question)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(w)
+				return 0, ErrAborted
+			}
+			return 0, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "q", "quit":
+			return 0, ErrAborted
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(candidates) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(w, "Unknown answer %q.\n", answer)
+	}
+}
+
+// reviewPlan asks the user whether to apply a proposed split, reading answers
+// from r and writing prompts to w until it gets a valid one. An empty answer
+// applies the plan. Only choiceAccept, choiceRegenerate, and choiceQuit are
+// returned.
+func reviewPlan(r *bufio.Reader, w io.Writer) (reviewChoice, error) {
+	for {
+		answer, err := term.Prompt(r, w,
+			"Apply this plan? [A]pply, [r]egenerate, [q]uit: ")
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(w)
+				return choiceQuit, nil
+			}
+			return choiceQuit, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "", "a", "apply", "y", "yes":
+			return choiceAccept, nil
+		case "r", "regenerate":
+			return choiceRegenerate, nil
+		case "q", "quit", "n", "no":
+			return choiceQuit, nil
+		}
+
+		fmt.Fprintf(w, "Unknown answer %q.\n", answer)
+	}
+}
@@ -0,0 +1,85 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reviewMessage", func() {
+	DescribeTable("maps answers to choices",
+		func(input string, expected reviewChoice) {
+			in := bufio.NewReader(strings.NewReader(input))
+
+			choice, err := reviewMessage(in, &bytes.Buffer{}, "Add greeting")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(choice).To(Equal(expected))
+		},
+		Entry("empty answer accepts", "\n", choiceAccept),
+		Entry("a accepts", "a\n", choiceAccept),
+		Entry("e edits", "e\n", choiceEdit),
+		Entry("Edit edits", "Edit\n", choiceEdit),
+		Entry("r regenerates", "r\n", choiceRegenerate),
+		Entry("q quits", "q\n", choiceQuit),
+		Entry("end of input quits", "", choiceQuit),
+	)
+
+	It("asks again after an unknown answer", func() {
+		out := &bytes.Buffer{}
+		in := bufio.NewReader(strings.NewReader("x\nr\n"))
+
+		choice, err := reviewMessage(in, out, "Add greeting")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(choice).To(Equal(choiceRegenerate))
+		Expect(out.String()).To(ContainSubstring(`Unknown answer "x"`))
+		Expect(strings.Count(out.String(), "[A]ccept")).To(Equal(2))
+	})
+
+	It("shows the message as it will be committed", func() {
+		out := &bytes.Buffer{}
+		in := bufio.NewReader(strings.NewReader("a\n"))
+
+		_, err := reviewMessage(in, out, "Add greeting\n\nSay hello.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(HavePrefix("Commit message:\n    Add greeting\n\n    Say hello.\n\nCommit with this message?"))
+	})
+})
+
+var _ = Describe("pickCandidate", func() {
+	candidates := []string{
+		"Add greeting\n\nSay hello to new users.",
+		"Greet new users",
+	}
+
+	It("lists the candidates and returns the picked index", func() {
+		out := &bytes.Buffer{}
+		in := bufio.NewReader(strings.NewReader("2\n"))
+
+		i, err := pickCandidate(in, out, candidates)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(i).To(Equal(1))
+		Expect(out.String()).To(ContainSubstring("[1] Add greeting\n"))
+		Expect(out.String()).To(ContainSubstring("    Say hello to new users.\n"))
+		Expect(out.String()).To(ContainSubstring("[2] Greet new users\n"))
+	})
+
+	It("asks again after an out-of-range answer", func() {
+		out := &bytes.Buffer{}
+		in := bufio.NewReader(strings.NewReader("3\n1\n"))
+
+		i, err := pickCandidate(in, out, candidates)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(i).To(Equal(0))
+		Expect(out.String()).To(ContainSubstring(`Unknown answer "3"`))
+	})
+
+	It("returns ErrAborted when the user quits", func() {
+		in := bufio.NewReader(strings.NewReader("q\n"))
+
+		_, err := pickCandidate(in, &bytes.Buffer{}, candidates)
+		Expect(err).To(MatchError(ErrAborted))
+	})
+})
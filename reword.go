@@ -0,0 +1,324 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// rewordMarker returns the line prefix that starts each commit's section in
+// the reword plan file.
+func rewordMarker(commentChar string) string {
+	return commentChar + " commit "
+}
+
+// Reword regenerates the message of every commit in revRange (a range such as
+// "main..HEAD", "HEAD~3..", or "abc123^!") from that commit's own diff, lets
+// the user review the new messages in one batch, and rewrites the current
+// branch with them. Trees, authors, and author dates are preserved.
+//
+// The range must only contain ancestors of HEAD, and there may be no merge
+// commits between the oldest commit in the range and HEAD.
+func Reword(ctx context.Context, config *Config, revRange string) error {
+	log.Infow("starting reword process",
+		"range", revRange)
+
+	// A single revision would select every commit back to the root.
+	if !isRange(revRange) {
+		return fmt.Errorf("%q is not a revision range; use a range such as %q", revRange, revRange+"..HEAD")
+	}
+
+	// 1. Work out which commits to reword and which must be replayed.
+	targets, err := git.RevList("--reverse", revRange)
+	if err != nil {
+		return fmt.Errorf("failed to list commits in %q: %w", revRange, err)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no commits in %q", revRange)
+	}
+
+	head, err := git.ReadCommit("HEAD")
+	if err != nil {
+		return err
+	}
+
+	oldest, err := git.ReadCommit(targets[0])
+	if err != nil {
+		return err
+	}
+
+	// replay holds every commit from the oldest target up to HEAD. Commits
+	// after a reworded one get new parents, so they must be rewritten too.
+	var base string
+	span := "HEAD"
+	if len(oldest.Parents) > 0 {
+		base = oldest.Parents[0]
+		span = base + "..HEAD"
+	}
+
+	merges, err := git.RevList("--merges", span)
+	if err != nil {
+		return err
+	}
+	if len(merges) > 0 {
+		return fmt.Errorf("cannot reword across merge commit %s", short(merges[0]))
+	}
+
+	replay, err := git.RevList("--reverse", span)
+	if err != nil {
+		return err
+	}
+	for _, id := range targets {
+		if !slices.Contains(replay, id) {
+			return fmt.Errorf("commit %s is not an ancestor of HEAD", short(id))
+		}
+	}
+
+	// 2. Generate a new message for each commit from its own diff.
+	include, exclude := excludePathspecs(config)
+	commits := make(map[string]*git.Commit, len(targets))
+	messages := make(map[string]string, len(targets))
+	for i, id := range targets {
+		commit, err := git.ReadCommit(id)
+		if err != nil {
+			return err
+		}
+		commits[id] = commit
+
+		diff, err := git.CommitDiff(id, exclude...)
+		if err != nil {
+			return fmt.Errorf("failed to get diff for %s: %w", short(id), err)
+		}
+		config.excluded = nil
+		if len(include) > 0 {
+			if config.excluded, err = git.CommitFiles(id, include...); err != nil {
+				return fmt.Errorf("failed to list excluded files for %s: %w", short(id), err)
+			}
+		}
+
+		if err := redactSecrets(config, &diff); err != nil {
+			return fmt.Errorf("commit %s: %w", short(id), err)
+		}
+		diff, err = fitDiff(ctx, config, diff)
+		if err != nil {
+			return err
+		}
+
+		prompt, err := commitPrompt(config, diff)
+		if err != nil {
+			return err
+		}
+
+		label := fmt.Sprintf("Generating message for %s (%d/%d)", short(id), i+1, len(targets))
+		message, err := generate(ctx, config, label, prompt)
+		if err == nil {
+			message, err = reviseMessage(ctx, config, prompt, message)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to generate message for %s: %w", short(id), err)
+		}
+		messages[id] = strings.TrimSpace(message)
+	}
+
+	// 3. Let the user review every message at once.
+	if !config.Yes {
+		messages, err = reviewRewordPlan(revRange, targets, commits, messages)
+		if err != nil {
+			return err
+		}
+	}
+
+	changed := 0
+	for _, id := range targets {
+		if messages[id] != commits[id].Message {
+			changed++
+		}
+	}
+	if changed == 0 {
+		fmt.Fprintln(os.Stderr, "No messages changed.")
+		return nil
+	}
+
+	// 4. Replay the commits onto the same base with the new messages.
+	parent := base
+	for _, id := range replay {
+		commit := commits[id]
+		if commit == nil {
+			if commit, err = git.ReadCommit(id); err != nil {
+				return err
+			}
+		}
+
+		// Unchanged messages are written exactly as they were. New ones get
+		// the trailing newline `git commit` would give them.
+		message := commit.RawMessage
+		if m, ok := messages[id]; ok && m != commit.Message {
+			message = m + "\n"
+		}
+
+		var parents []string
+		if parent != "" {
+			parents = []string{parent}
+		}
+		parent, err = git.CommitTree(commit.Tree, parents, message, commit.AuthorEnv())
+		if err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", short(id), err)
+		}
+	}
+
+	if err := git.UpdateRef("HEAD", parent, head.ID, "auto-commit: reword "+revRange); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Reworded %d of %d commits.\n", changed, len(targets))
+	return nil
+}
+
+// reviewRewordPlan writes the proposed messages to a single file, opens it in
+// the user's editor, and returns the edited messages. A commit whose section
+// is emptied or removed keeps its original message.
+func reviewRewordPlan(
+	revRange string,
+	targets []string,
+	commits map[string]*git.Commit,
+	messages map[string]string,
+) (map[string]string, error) {
+	tempDir, err := os.MkdirTemp("", "git-auto-commit-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	var all strings.Builder
+	for _, id := range targets {
+		fmt.Fprintf(&all, "%s\n%s\n", commits[id].Message, messages[id])
+	}
+	char, err := readCommentChar(all.String())
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(tempDir, "REWORD_EDITMSG")
+	plan := formatRewordPlan(char, revRange, targets, commits, messages)
+	if err := os.WriteFile(path, []byte(plan), 0644); err != nil {
+		return nil, err
+	}
+
+	if err := runEditor(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	edited, err := parseRewordPlan(char, bufio.NewScanner(f))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(targets))
+	for _, id := range targets {
+		if message := edited[id]; message != "" {
+			result[id] = message
+		} else {
+			result[id] = commits[id].Message
+		}
+	}
+	return result, nil
+}
+
+// formatRewordPlan renders the batch review file for Reword, with comments
+// starting with commentChar.
+func formatRewordPlan(
+	commentChar string,
+	revRange string,
+	targets []string,
+	commits map[string]*git.Commit,
+	messages map[string]string,
+) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s Reword %d commits in %s.\n", commentChar, len(targets), revRange)
+	fmt.Fprintf(&b, "%s\n", commentChar)
+	fmt.Fprintf(&b, "%s Edit the messages below, then save and close the editor. Lines\n", commentChar)
+	fmt.Fprintf(&b, "%s starting with '%s' are ignored. Leave a message empty to keep the\n", commentChar, commentChar)
+	fmt.Fprintf(&b, "%s commit's original message. Do not change the '%s' lines.\n",
+		commentChar, strings.TrimSpace(rewordMarker(commentChar)))
+
+	for _, id := range targets {
+		original, _, _ := strings.Cut(commits[id].Message, "\n")
+		fmt.Fprintf(&b, "\n%s%s %s\n", rewordMarker(commentChar), id, original)
+		fmt.Fprintf(&b, "%s\n", messages[id])
+	}
+	return b.String()
+}
+
+// parseRewordPlan reads an edited reword plan and returns the message for
+// each commit section, with lines starting with commentChar removed.
+func parseRewordPlan(commentChar string, scanner *bufio.Scanner) (map[string]string, error) {
+	var (
+		marker   = rewordMarker(commentChar)
+		messages = make(map[string]string)
+		current  string
+		lines    []string
+	)
+
+	flush := func() {
+		if current != "" {
+			messages[current] = strings.TrimSpace(strings.Join(lines, "\n"))
+		}
+		lines = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, marker); ok {
+			flush()
+			current, _, _ = strings.Cut(rest, " ")
+			continue
+		}
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return messages, scanner.Err()
+}
+
+// isRange reports whether rev names a range of commits rather than a single
+// revision: "A..B", "A...B", "A..", "..B", "A^!", or "A^-".
+func isRange(rev string) bool {
+	if strings.HasPrefix(rev, "-") {
+		return false
+	}
+	if strings.Contains(rev, "..") || strings.HasSuffix(rev, "^!") {
+		return true
+	}
+	i := strings.LastIndex(rev, "^-")
+	if i < 0 {
+		return false
+	}
+	_, err := strconv.Atoi(rev[i+2:])
+	return rev[i+2:] == "" || err == nil
+}
+
+// short abbreviates a commit hash for display.
+func short(id string) string {
+	if len(id) > 7 {
+		return id[:7]
+	}
+	return id
+}
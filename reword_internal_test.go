@@ -0,0 +1,84 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/util/git"
+)
+
+var _ = Describe("reword plans", func() {
+	targets := []string{"c1", "c2"}
+	commits := map[string]*git.Commit{
+		"c1": {ID: "c1", Message: "wip"},
+		"c2": {ID: "c2", Message: "fix\n\nsome details"},
+	}
+
+	It("round-trips generated messages", func() {
+		messages := map[string]string{
+			"c1": "Add first\n\nWith a body.",
+			"c2": "Add second",
+		}
+
+		plan := formatRewordPlan("#", "main..HEAD", targets, commits, messages)
+		Expect(plan).To(ContainSubstring("# commit c1 wip\n"))
+		Expect(plan).To(ContainSubstring("# commit c2 fix\n"))
+
+		parsed, err := parseRewordPlan("#", bufio.NewScanner(strings.NewReader(plan)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(messages))
+	})
+
+	It("drops comments and leaves emptied sections blank", func() {
+		plan := strings.Join([]string{
+			"# header",
+			"# commit c1 wip",
+			"Edited subject",
+			"# a note to self",
+			"",
+			"Edited body.",
+			"# commit c2 fix",
+			"",
+		}, "\n")
+
+		parsed, err := parseRewordPlan("#", bufio.NewScanner(strings.NewReader(plan)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(map[string]string{
+			"c1": "Edited subject\n\nEdited body.",
+			"c2": "",
+		}))
+	})
+
+	It("keeps lines starting with '#' under another comment character", func() {
+		messages := map[string]string{
+			"c1": "Follow up on review\n\n#123 asked for this.",
+			"c2": "Add second",
+		}
+
+		plan := formatRewordPlan(";", "main..HEAD", targets, commits, messages)
+		Expect(plan).To(HavePrefix("; Reword 2 commits"))
+		Expect(plan).To(ContainSubstring("; commit c1 wip\n"))
+
+		parsed, err := parseRewordPlan(";", bufio.NewScanner(strings.NewReader(plan)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(messages))
+	})
+
+	DescribeTable("tells ranges from single revisions",
+		func(rev string, want bool) {
+			Expect(isRange(rev)).To(Equal(want))
+		},
+		Entry("two dots", "main..HEAD", true),
+		Entry("three dots", "main...HEAD", true),
+		Entry("open end", "HEAD~3..", true),
+		Entry("a single commit", "abc123^!", true),
+		Entry("parents excluded", "abc123^-", true),
+		Entry("nth parent excluded", "abc123^-2", true),
+		Entry("a revision", "HEAD~3", false),
+		Entry("a branch", "main", false),
+		Entry("an option", "--all", false),
+	)
+})
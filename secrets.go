@@ -0,0 +1,78 @@
+package git_auto_commit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/redact"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// ErrSecretsFound is returned when likely secrets are found in a diff and
+// config.Secrets is set to abort.
+var ErrSecretsFound = errors.New("possible secrets found in the changes")
+
+// Secret handling modes for config.Secrets.
+const (
+	// secretsRedact masks secrets before diffs are sent to the provider.
+	secretsRedact = "redact"
+	// secretsAbort refuses to send diffs that contain secrets.
+	secretsAbort = "abort"
+	// secretsOff sends diffs without scanning them.
+	secretsOff = "off"
+)
+
+// redactSecrets scans each text for likely secrets and, depending on
+// config.Secrets, masks them in place or returns ErrSecretsFound listing
+// where they are. Either way, nothing is changed unless every text could be
+// scanned.
+func redactSecrets(config *Config, texts ...*string) error {
+	mode := config.Secrets
+	if mode == "" {
+		mode = secretsRedact
+	}
+	switch mode {
+	case secretsOff:
+		return nil
+	case secretsRedact, secretsAbort:
+	default:
+		return fmt.Errorf("unknown secrets mode %q", mode)
+	}
+
+	redactor, err := redact.New(config.SecretPatterns)
+	if err != nil {
+		return err
+	}
+
+	var (
+		redacted = make([]string, len(texts))
+		findings []string
+	)
+	for i, text := range texts {
+		var found []redact.Finding
+		redacted[i], found = redactor.Redact(*text)
+		for _, f := range found {
+			findings = append(findings, f.String())
+		}
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	log.Warnw("possible secrets found in the changes",
+		"findings", findings)
+
+	if mode == secretsAbort {
+		return fmt.Errorf("%w:\n  %s\nRemove them, or set auto-commit.secrets to %q to mask them.",
+			ErrSecretsFound, strings.Join(findings, "\n  "), secretsRedact)
+	}
+
+	fmt.Fprintf(os.Stderr, "Masked %d possible secrets before sending the changes:\n  %s\n\n",
+		len(findings), strings.Join(findings, "\n  "))
+	for i, text := range texts {
+		*text = redacted[i]
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+package git_auto_commit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/template"
+	"github.com/ivy/git-auto-commit/util/diff"
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+	"github.com/ivy/git-auto-commit/util/term"
+	"github.com/ivy/git-auto-commit/util/tokens"
+)
+
+// splitHunk is one piece of the staged changes that the model can assign to a
+// commit. Most are a single hunk, but files that must be applied as a whole
+// (created, deleted, and binary files, and mode changes) are one piece with
+// all of their hunks.
+type splitHunk struct {
+	ID    string
+	File  *diff.File
+	Hunks []*diff.Hunk
+
+	// Excluded hides the piece's contents from the model, leaving only its
+	// path.
+	Excluded bool
+
+	// Text is the patch as shown to the model, with any secrets masked.
+	Text string
+}
+
+// splitGroup is a commit in a split plan.
+type splitGroup struct {
+	Message string
+	Hunks   []*splitHunk
+}
+
+// Patch renders the group's hunks as a single patch, in their original order.
+// Hunk headers are adjusted for the hunks that have already been applied by
+// earlier groups, so that each one applies exactly where it was staged.
+func (g *splitGroup) Patch(applied map[*diff.Hunk]bool) string {
+	var (
+		b     strings.Builder
+		file  *diff.File
+		hunks []*diff.Hunk
+	)
+	flush := func() {
+		if file != nil {
+			b.WriteString(file.Patch(moveHunks(file, hunks, applied)...))
+		}
+	}
+	for _, h := range g.Hunks {
+		if h.File != file {
+			flush()
+			file, hunks = h.File, nil
+		}
+		hunks = append(hunks, h.Hunks...)
+	}
+	flush()
+	return b.String()
+}
+
+// moveHunks returns copies of the selected hunks of f with their start lines
+// adjusted for a file that only has the applied hunks applied to it.
+func moveHunks(f *diff.File, selected []*diff.Hunk, applied map[*diff.Hunk]bool) []*diff.Hunk {
+	var (
+		moved     []*diff.Hunk
+		staged    int // line delta of every earlier hunk in the staged diff
+		done      int // line delta of earlier hunks already in the index
+		inPatch   int // line delta of earlier hunks in this patch
+		remaining = selected
+	)
+	for _, h := range f.Hunks {
+		oldStart, oldLines, newStart, newLines := h.Range()
+		delta := newLines - oldLines
+
+		if len(remaining) > 0 && remaining[0] == h {
+			moved = append(moved, h.Moved(oldStart+done, newStart-staged+done+inPatch))
+			remaining = remaining[1:]
+			inPatch += delta
+		} else if applied[h] {
+			done += delta
+		}
+		staged += delta
+	}
+	return moved
+}
+
+// splitPlanResponse is the JSON the model is asked to respond with.
+type splitPlanResponse struct {
+	Commits []struct {
+		Message string   `json:"message"`
+		Hunks   []string `json:"hunks"`
+	} `json:"commits"`
+}
+
+// Split asks the model to divide the staged changes into several focused
+// commits, shows the proposed plan for approval, and then commits each group
+// of hunks in turn. The working tree is never touched. If anything fails
+// midway, the branch and index are restored to where they started.
+func Split(ctx context.Context, config *Config) error {
+	log.Infow("starting split process",
+		"extra_args", config.ExtraArgs)
+
+	if config.Amend {
+		return errors.New("--split can't be combined with --amend")
+	}
+
+	// Check before asking for a plan that could never be applied.
+	head, err := git.RevParse("HEAD")
+	if err != nil {
+		return errors.New("--split needs at least one existing commit")
+	}
+
+	patch, err := git.StagedPatch()
+	if err != nil {
+		log.Errorw("failed to get staged changes",
+			"error", err)
+		return err
+	}
+	files, err := diff.Parse(patch)
+	if err != nil {
+		return fmt.Errorf("failed to parse staged changes: %w", err)
+	}
+
+	// Excluded files can still be split into commits, but only by name.
+	var excluded []string
+	if include, _ := excludePathspecs(config); len(include) > 0 {
+		if excluded, err = git.StagedFiles("", include...); err != nil {
+			return fmt.Errorf("failed to list excluded files: %w", err)
+		}
+	}
+
+	if excluded, err = fitSplit(config, files, excluded); err != nil {
+		return err
+	}
+
+	hunks := splitHunks(files, excluded)
+	if len(hunks) == 0 {
+		return errors.New("no changes are staged")
+	}
+
+	texts := make([]*string, len(hunks))
+	for i, h := range hunks {
+		texts[i] = &h.Text
+	}
+	if err := redactSecrets(config, texts...); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	interactive := !config.Yes && term.IsTerminal(os.Stdin)
+
+	for {
+		groups, err := planSplit(ctx, config, hunks)
+		if err != nil {
+			log.Errorw("failed to plan split",
+				"error", err)
+			return err
+		}
+		printSplitPlan(os.Stderr, groups)
+
+		if !interactive {
+			return applySplit(config, head, groups)
+		}
+
+		choice, err := reviewPlan(in, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		switch choice {
+		case choiceAccept:
+			return applySplit(config, head, groups)
+		case choiceQuit:
+			return ErrAborted
+		case choiceRegenerate:
+			guidance, err := term.Prompt(in, os.Stderr, "Additional guidance (optional): ")
+			if err != nil {
+				return err
+			}
+			if guidance != "" {
+				config.Message = strings.TrimSpace(config.Message + "\n" + guidance)
+			}
+		}
+	}
+}
+
+// fitSplit returns excluded with more files added, if the changes the model
+// would see are over the token budget. As with the truncate strategy,
+// generated files go first, then the largest. Their pieces are then kept
+// whole and shown by name only, so that they can still be assigned to a
+// commit. The summarize strategy can't be used to plan a split, since the
+// model must see the hunks it groups, so it's treated as truncate.
+func fitSplit(config *Config, files []*diff.File, excluded []string) ([]string, error) {
+	strategy, err := diffStrategy(config)
+	if err != nil || strategy == strategyFull {
+		return excluded, err
+	}
+
+	var (
+		visible  []*diff.File
+		estimate int
+	)
+	for _, f := range files {
+		if !slices.Contains(excluded, f.Path()) {
+			visible = append(visible, f)
+			estimate += tokens.Estimate(f.String())
+		}
+	}
+	budget := tokenBudget(config)
+	if estimate <= budget {
+		return excluded, nil
+	}
+
+	log.Infow("staged diff is over the token budget, hiding files from the split plan",
+		"estimate", estimate,
+		"budget", budget)
+
+	omitted := omitFiles(visible, budget)
+	excluded = slices.Clip(excluded)
+	for _, f := range visible {
+		if _, ok := omitted[f]; ok {
+			excluded = append(excluded, f.Path())
+		}
+	}
+	return excluded, nil
+}
+
+// splitHunks numbers the pieces of the staged changes that can be committed
+// separately. Files with an excluded path are kept whole, since the model
+// can't see their hunks.
+func splitHunks(files []*diff.File, excluded []string) []*splitHunk {
+	var hunks []*splitHunk
+	add := func(f *diff.File, h ...*diff.Hunk) {
+		hunks = append(hunks, &splitHunk{
+			ID:       strconv.Itoa(len(hunks) + 1),
+			File:     f,
+			Hunks:    h,
+			Excluded: slices.Contains(excluded, f.Path()),
+			Text:     f.Patch(h...),
+		})
+	}
+
+	for _, f := range files {
+		atomic := f.IsNew() || f.IsDeleted() || f.IsBinary() || len(f.Hunks) == 0 ||
+			slices.Contains(excluded, f.Path())
+		if atomic {
+			add(f, f.Hunks...)
+			continue
+		}
+		for _, h := range f.Hunks {
+			add(f, h)
+		}
+	}
+	return hunks
+}
+
+// planSplit asks the model how to group the hunks into commits.
+func planSplit(ctx context.Context, config *Config, hunks []*splitHunk) ([]*splitGroup, error) {
+	// Only the hunks the model can see are used to suggest a type and scope.
+	var patch strings.Builder
+	for _, h := range hunks {
+		if !h.Excluded {
+			patch.WriteString(h.Text)
+		}
+	}
+	format, err := commitFormat(config, patch.String())
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := template.RenderString("prompt/split.tmpl", map[string]any{
+		"Hunks":   hunks,
+		"Format":  format,
+		"Style":   repoStyle(config),
+		"Message": config.Message,
+	})
+	if err != nil {
+		log.Errorw("failed to execute split template",
+			"error", err)
+		return nil, err
+	}
+	log.Debugw("split template executed", "prompt", prompt)
+
+	response, err := generateQuietly(ctx, config,
+		fmt.Sprintf("Planning commits for %d hunks", len(hunks)), prompt)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugw("generated split plan", "response", response)
+
+	groups, err := parseSplitPlan(response, hunks)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		// Revisions are asked for as if the group were a commit of its own,
+		// rather than resending the whole plan.
+		groupPrompt, err := g.prompt(config)
+		if err != nil {
+			return nil, err
+		}
+		if g.Message, err = reviseMessage(ctx, config, groupPrompt, g.Message); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// prompt returns the prompt for writing the group's message on its own, with
+// only the group's hunks.
+func (g *splitGroup) prompt(config *Config) (string, error) {
+	var (
+		patch    strings.Builder
+		excluded []string
+	)
+	for _, h := range g.Hunks {
+		if h.Excluded {
+			excluded = append(excluded, h.File.Path())
+			continue
+		}
+		patch.WriteString(h.Text)
+	}
+	return renderCommitPrompt(config, patch.String(), excluded)
+}
+
+// parseSplitPlan decodes the model's response into commit groups. Each hunk
+// must be assigned exactly once; hunks the model forgot are added to the last
+// commit rather than being left staged.
+func parseSplitPlan(response string, hunks []*splitHunk) ([]*splitGroup, error) {
+	// Models often wrap JSON in prose or code fences, so only decode the
+	// outermost object.
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("split plan is not a JSON object")
+	}
+
+	var plan splitPlanResponse
+	if err := json.Unmarshal([]byte(response[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode split plan: %w", err)
+	}
+
+	byID := make(map[string]*splitHunk, len(hunks))
+	for _, h := range hunks {
+		byID[h.ID] = h
+	}
+
+	var (
+		groups   []*splitGroup
+		assigned = make(map[string]bool, len(hunks))
+	)
+	for _, c := range plan.Commits {
+		group := &splitGroup{Message: strings.TrimSpace(c.Message)}
+		for _, id := range c.Hunks {
+			h, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("split plan refers to unknown hunk %q", id)
+			}
+			if assigned[id] {
+				return nil, fmt.Errorf("split plan assigns hunk %s more than once", id)
+			}
+			assigned[id] = true
+			group.Hunks = append(group.Hunks, h)
+		}
+		if len(group.Hunks) == 0 {
+			continue
+		}
+		if group.Message == "" {
+			return nil, errors.New("split plan has a commit without a message")
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("split plan has no commits")
+	}
+
+	last := groups[len(groups)-1]
+	for _, h := range hunks {
+		if !assigned[h.ID] {
+			log.Warnw("split plan left a hunk out, adding it to the last commit",
+				"hunk", h.ID,
+				"path", h.File.Path())
+			last.Hunks = append(last.Hunks, h)
+		}
+	}
+
+	// Patches must list a file's hunks in their original order.
+	order := make(map[*splitHunk]int, len(hunks))
+	for i, h := range hunks {
+		order[h] = i
+	}
+	for _, g := range groups {
+		slices.SortFunc(g.Hunks, func(a, b *splitHunk) int {
+			return order[a] - order[b]
+		})
+	}
+
+	return groups, nil
+}
+
+// printSplitPlan writes a summary of the plan to w.
+func printSplitPlan(w io.Writer, groups []*splitGroup) {
+	for i, g := range groups {
+		subject, _, _ := strings.Cut(g.Message, "\n")
+		fmt.Fprintf(w, "[%d/%d] %s\n", i+1, len(groups), subject)
+
+		var paths []string
+		added, deleted := 0, 0
+		for _, h := range g.Hunks {
+			if !slices.Contains(paths, h.File.Path()) {
+				paths = append(paths, h.File.Path())
+			}
+			for _, hunk := range h.Hunks {
+				a, d := hunk.Stat()
+				added += a
+				deleted += d
+			}
+		}
+		fmt.Fprintf(w, "      %s (+%d -%d)\n\n", strings.Join(paths, ", "), added, deleted)
+	}
+}
+
+// applySplit commits each group in turn by resetting the index to head and
+// applying the group's hunks to it. If any step fails, the branch and index
+// are restored to their original state.
+func applySplit(config *Config, head string, groups []*splitGroup) error {
+	tree, err := git.WriteTree()
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+
+	restore := func() {
+		if err := git.ResetSoft(head); err != nil {
+			log.Errorw("failed to restore the branch",
+				"head", head,
+				"error", err)
+		}
+		if err := git.ReadTree(tree); err != nil {
+			log.Errorw("failed to restore the index",
+				"tree", tree,
+				"error", err)
+		}
+	}
+
+	if err := git.ReadTree("HEAD"); err != nil {
+		restore()
+		return fmt.Errorf("failed to unstage changes: %w", err)
+	}
+
+	applied := make(map[*diff.Hunk]bool)
+	for i, g := range groups {
+		if err := git.ApplyCached(g.Patch(applied)); err != nil {
+			restore()
+			return fmt.Errorf("failed to stage commit %d of %d: %w", i+1, len(groups), err)
+		}
+		for _, h := range g.Hunks {
+			for _, hunk := range h.Hunks {
+				applied[hunk] = true
+			}
+		}
+		if err := commit(config, g.Message); err != nil {
+			restore()
+			return fmt.Errorf("failed to create commit %d of %d: %w", i+1, len(groups), err)
+		}
+	}
+
+	return nil
+}
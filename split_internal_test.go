@@ -0,0 +1,133 @@
+package git_auto_commit
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/config"
+	"github.com/ivy/git-auto-commit/util/diff"
+)
+
+var _ = Describe("parseSplitPlan", func() {
+	var hunks []*splitHunk
+
+	BeforeEach(func() {
+		files, err := diff.Parse("diff --git a/a.go b/a.go\n" +
+			"--- a/a.go\n" +
+			"+++ b/a.go\n" +
+			"@@ -1 +1 @@\n" +
+			"-one\n" +
+			"+uno\n" +
+			"@@ -9 +9 @@\n" +
+			"-nine\n" +
+			"+nueve\n")
+		Expect(err).NotTo(HaveOccurred())
+		hunks = splitHunks(files, nil)
+		Expect(hunks).To(HaveLen(2))
+	})
+
+	It("adds hunks the model left out to the last commit", func() {
+		groups, err := parseSplitPlan(`{"commits": [{"message": "Translate one", "hunks": ["1"]}]}`, hunks)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+		Expect(groups[0].Hunks).To(Equal(hunks))
+	})
+
+	It("rejects hunks assigned twice", func() {
+		_, err := parseSplitPlan(`{"commits": [
+			{"message": "First", "hunks": ["1"]},
+			{"message": "Second", "hunks": ["1", "2"]}
+		]}`, hunks)
+		Expect(err).To(MatchError("split plan assigns hunk 1 more than once"))
+	})
+
+	It("rejects responses without JSON", func() {
+		_, err := parseSplitPlan("I can't do that.", hunks)
+		Expect(err).To(MatchError("split plan is not a JSON object"))
+	})
+})
+
+var _ = Describe("splitGroup.Patch", func() {
+	It("moves hunks to account for earlier groups", func() {
+		files, err := diff.Parse("diff --git a/a.go b/a.go\n" +
+			"--- a/a.go\n" +
+			"+++ b/a.go\n" +
+			"@@ -1,2 +1,3 @@\n" +
+			" one\n" +
+			"+one and a half\n" +
+			" two\n" +
+			"@@ -9,2 +10,3 @@\n" +
+			" nine\n" +
+			"+nine and a half\n" +
+			" ten\n")
+		Expect(err).NotTo(HaveOccurred())
+		hunks := splitHunks(files, nil)
+
+		// Committing the second hunk first: it applies at its original place.
+		second := &splitGroup{Hunks: []*splitHunk{hunks[1]}}
+		Expect(second.Patch(nil)).To(ContainSubstring("@@ -9,2 +9,3 @@\n"))
+
+		// The first hunk comes after, and doesn't move.
+		first := &splitGroup{Hunks: []*splitHunk{hunks[0]}}
+		applied := map[*diff.Hunk]bool{files[0].Hunks[1]: true}
+		Expect(first.Patch(applied)).To(ContainSubstring("@@ -1,2 +1,3 @@\n"))
+
+		// In the opposite order, the second hunk shifts down by one line.
+		applied = map[*diff.Hunk]bool{files[0].Hunks[0]: true}
+		Expect(second.Patch(applied)).To(ContainSubstring("@@ -10,2 +10,3 @@\n"))
+	})
+})
+
+var _ = Describe("fitSplit", func() {
+	var (
+		cfg   *Config
+		files []*diff.File
+	)
+
+	BeforeEach(func() {
+		cfg = &Config{Config: &config.Config{Model: "fake-model", TokenBudget: 100}}
+
+		var err error
+		files, err = diff.Parse(fileDiff("main.go", 3) + fileDiff("yarn.lock", 200) + fileDiff("big.go", 200))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("hides generated and then the largest files by name", func() {
+		excluded, err := fitSplit(cfg, files, []string{".env"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excluded).To(Equal([]string{".env", "yarn.lock", "big.go"}))
+
+		hunks := splitHunks(files, excluded)
+		Expect(hunks).To(HaveLen(3))
+		Expect(hunks[0].Excluded).To(BeFalse())
+		Expect(hunks[1].Excluded).To(BeTrue())
+		Expect(hunks[2].Excluded).To(BeTrue())
+	})
+
+	It("leaves changes within the budget alone", func() {
+		cfg.TokenBudget = 100000
+		Expect(fitSplit(cfg, files, nil)).To(BeEmpty())
+	})
+
+	It("shows everything with the full strategy", func() {
+		cfg.DiffStrategy = strategyFull
+		Expect(fitSplit(cfg, files, nil)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("splitGroup.prompt", func() {
+	It("mentions the group's excluded files without changing the Config", func() {
+		files, err := diff.Parse(fileDiff("main.go", 1) + fileDiff("secret.env", 1))
+		Expect(err).NotTo(HaveOccurred())
+		hunks := splitHunks(files, []string{"secret.env"})
+
+		cfg := &Config{Config: &config.Config{Model: "fake-model"}}
+		cfg.excluded = []string{"other.env"}
+
+		prompt, err := (&splitGroup{Hunks: hunks}).prompt(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompt).To(ContainSubstring("secret.env"))
+		Expect(prompt).NotTo(ContainSubstring("other.env"))
+		Expect(cfg.excluded).To(Equal([]string{"other.env"}))
+	})
+})
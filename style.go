@@ -0,0 +1,78 @@
+package git_auto_commit
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/message"
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// styleExamples is the number of past messages shown to the model as
+// examples of the repository's style.
+const styleExamples = 3
+
+// botAuthor matches the names of bots that commit to repositories, whose
+// messages don't reflect the team's style.
+var botAuthor = regexp.MustCompile(`(?i)\[bot\]|^(dependabot|renovate|github-actions|greenkeeper|snyk-bot|pre-commit-ci)\b`)
+
+// generatedSubjects are subject prefixes of messages written by Git itself
+// rather than by people.
+var generatedSubjects = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// repoStyle learns the style of the repository's recent commit messages, at
+// most once per Config. It returns nil if style learning is disabled or the
+// history is too short to learn from.
+func repoStyle(config *Config) *message.Style {
+	if config.StyleSamples <= 0 {
+		return nil
+	}
+	if config.style == nil {
+		config.style = learnStyle(config.StyleSamples)
+	}
+	if len(config.style.Conventions) == 0 {
+		return nil
+	}
+	return config.style
+}
+
+// learnStyle samples up to n recent commits, leaving out bots and messages
+// generated by Git, and learns their style.
+func learnStyle(n int) *message.Style {
+	commits, err := git.RecentCommits(n)
+	if err != nil {
+		// New repositories have no history to learn from.
+		log.Debugw("failed to read recent commits, skipping style",
+			"error", err)
+		return &message.Style{}
+	}
+
+	var messages []string
+	for _, c := range commits {
+		if isBotCommit(c) {
+			continue
+		}
+		messages = append(messages, c.Message)
+	}
+
+	style := message.LearnStyle(messages, styleExamples)
+	log.Debugw("learned commit style",
+		"sampled", len(messages),
+		"conventions", style.Conventions)
+	return style
+}
+
+// isBotCommit reports whether a commit was made by a bot or its message was
+// generated by Git.
+func isBotCommit(c *git.Commit) bool {
+	if botAuthor.MatchString(c.AuthorName) || strings.Contains(c.AuthorEmail, "[bot]") {
+		return true
+	}
+	for _, prefix := range generatedSubjects {
+		if strings.HasPrefix(c.Message, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package template
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/ivy/git-auto-commit/util/diff"
+	"github.com/ivy/git-auto-commit/util/tokens"
+)
+
+// funcs are the helper functions available to every template, embedded or
+// not. Like text/template's own functions, the value being piped in is the
+// last argument, so that `{{.Staged | truncate 2000}}` works.
+var funcs = template.FuncMap{
+	"truncate":     truncate,
+	"indent":       indent,
+	"wrap":         wrap,
+	"join":         join,
+	"default":      defaultValue,
+	"contains":     contains,
+	"hasPrefix":    hasPrefix,
+	"hasSuffix":    hasSuffix,
+	"regexReplace": regexReplace,
+	"env":          env,
+	"extStats":     extStats,
+}
+
+// truncate cuts text down to about n tokens, at a line boundary.
+func truncate(n int, text string) string {
+	return tokens.Truncate(text, n)
+}
+
+// indent prefixes every non-empty line of text with n spaces.
+func indent(n int, text string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap breaks each line of text at spaces so that no line is longer than
+// width, where possible.
+func wrap(width int, text string) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		current := ""
+		for _, word := range strings.Fields(line) {
+			switch {
+			case current == "":
+				current = word
+			case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width:
+				out = append(out, current)
+				current = word
+			default:
+				current += " " + word
+			}
+		}
+		out = append(out, current)
+	}
+	return strings.Join(out, "\n")
+}
+
+// join joins a list of strings with sep.
+func join(sep string, items []string) string {
+	return strings.Join(items, sep)
+}
+
+// defaultValue returns value, or fallback if value is empty.
+func defaultValue(fallback string, value string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// contains reports whether text contains substr.
+func contains(substr, text string) bool {
+	return strings.Contains(text, substr)
+}
+
+// hasPrefix reports whether text begins with prefix.
+func hasPrefix(prefix, text string) bool {
+	return strings.HasPrefix(text, prefix)
+}
+
+// hasSuffix reports whether text ends with suffix.
+func hasSuffix(suffix, text string) bool {
+	return strings.HasSuffix(text, suffix)
+}
+
+// regexReplace replaces every match of pattern in text with replacement,
+// which may refer to groups as "$1". It returns an error if the pattern is
+// invalid, which stops the template.
+func regexReplace(pattern, replacement, text string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(text, replacement), nil
+}
+
+// secretEnv matches the names of environment variables that may hold
+// credentials, which templates can't read.
+var secretEnv = regexp.MustCompile(`(?i)KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH`)
+
+// env returns the value of an environment variable, or "" if it's unset or its
+// name suggests it holds a credential, so that a template can't leak API keys
+// into a prompt.
+func env(name string) string {
+	if secretEnv.MatchString(name) {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// ExtStat counts the changes to files with one extension in a diff.
+type ExtStat struct {
+	// Ext is the extension without its dot, such as "go", or "" for files
+	// without one.
+	Ext     string
+	Files   int
+	Added   int
+	Deleted int
+}
+
+// extStats counts the files and lines changed in a diff by extension, most
+// changed files first. It returns nothing if the text isn't a diff, such as
+// when it has been summarized.
+func extStats(text string) []ExtStat {
+	files, err := diff.Parse(text)
+	if err != nil {
+		return nil
+	}
+
+	var stats []ExtStat
+	for _, f := range files {
+		ext := strings.TrimPrefix(path.Ext(f.Path()), ".")
+		i := slices.IndexFunc(stats, func(s ExtStat) bool { return s.Ext == ext })
+		if i < 0 {
+			stats = append(stats, ExtStat{Ext: ext})
+			i = len(stats) - 1
+		}
+		added, deleted := f.Stat()
+		stats[i].Files++
+		stats[i].Added += added
+		stats[i].Deleted += deleted
+	}
+
+	slices.SortStableFunc(stats, func(a, b ExtStat) int {
+		return b.Files - a.Files
+	})
+	return stats
+}
@@ -1,14 +1,19 @@
 // Package template provides a simple wrapper around the text/template package
 // for rendering templates with data. It supports loading templates from
-// embedded files and provides methods for rendering templates to bytes,
-// strings, and io.Reader.
+// override directories and embedded files, and provides methods for rendering
+// templates to bytes, strings, and io.Reader.
 package template
 
 import (
 	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
 	"sync"
 	"text/template"
 )
@@ -21,18 +26,103 @@ var fs embed.FS
 type Engine struct {
 	mu        sync.RWMutex
 	templates map[string]*template.Template
+
+	// dirs are searched in order for a template before the embedded files.
+	dirs []string
 }
 
 // engine is the default template engine instance.
 var engine = New()
 
-// New creates a new template engine. It parses all templates in the templates
-// directory and returns an Engine instance. If parsing fails, it returns
-// an error.
-func New() *Engine {
+// New creates a new template engine that looks up templates in each of the
+// given directories, in order, before falling back to the embedded files.
+func New(dirs ...string) *Engine {
 	return &Engine{
 		templates: make(map[string]*template.Template),
+		dirs:      dirs,
+	}
+}
+
+// Dirs returns the directories that override the embedded templates: the
+// repository's ".git-auto-commit/templates" directory, if repoRoot is set, and
+// then "$XDG_CONFIG_HOME/git-auto-commit/templates", which defaults to
+// "~/.config/git-auto-commit/templates".
+func Dirs(repoRoot string) []string {
+	var dirs []string
+	if repoRoot != "" {
+		dirs = append(dirs, RepoDir(repoRoot))
+	}
+	if dir := UserDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// RepoDir returns the directory of a repository's template overrides.
+func RepoDir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".git-auto-commit", "templates")
+}
+
+// UserDir returns the directory of the user's template overrides, or "" if
+// neither $XDG_CONFIG_HOME nor the home directory are known.
+func UserDir() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
 	}
+	return filepath.Join(configHome, "git-auto-commit", "templates")
+}
+
+// SetDirs sets the override directories of the default engine.
+func SetDirs(dirs ...string) {
+	engine.SetDirs(dirs...)
+}
+
+// Names returns the names of the embedded templates, such as
+// "prompt/commit.tmpl".
+func Names() ([]string, error) {
+	return iofs.Glob(fs, "*/*.tmpl")
+}
+
+// Default returns the text of the embedded template with the given name,
+// ignoring any overrides.
+func Default(name string) ([]byte, error) {
+	return fs.ReadFile(name)
+}
+
+// Eject copies the embedded template with the given name into dir, where it
+// overrides the default and can be edited, and returns the path of the copy.
+// It returns an error rather than overwrite an existing file.
+func Eject(name, dir string) (string, error) {
+	text, err := Default(name)
+	if err != nil {
+		return "", fmt.Errorf("no default template named %q", name)
+	}
+
+	dest := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(text); err != nil {
+		return "", err
+	}
+	return dest, f.Close()
+}
+
+// Source returns the path of the file the default engine loads a template
+// from, or "" if it uses the embedded one.
+func Source(name string) (string, error) {
+	return engine.Source(name)
 }
 
 // Lookup returns a template by name. If the template is not found, it
@@ -42,6 +132,11 @@ func Lookup(name string) (*template.Template, error) {
 	return engine.Lookup(name)
 }
 
+// Validate parses every template of the default engine, returning any errors.
+func Validate() error {
+	return engine.Validate()
+}
+
 // RenderBytes returns a byte slice for the rendered template.
 func RenderBytes(name string, data any) ([]byte, error) {
 	return engine.RenderBytes(name, data)
@@ -57,19 +152,83 @@ func Render(name string, data any) (io.Reader, error) {
 	return engine.Render(name, data)
 }
 
-// lookup parses a template from the embedded file system. It caches the parsed
-// template in the engine's templates map for future use. If parsing fails, it
-// returns an error.
-func (e *Engine) lookup(name string) (*template.Template, error) {
-	tmpl, err := template.ParseFS(fs, name)
+// SetDirs replaces the engine's override directories and forgets any
+// templates it has already loaded.
+func (e *Engine) SetDirs(dirs ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.dirs = dirs
+	e.templates = make(map[string]*template.Template)
+}
+
+// Source returns the path of the file that overrides the named template, or ""
+// if none does and the embedded template is used. It returns an error if an
+// override directory can't be read.
+func (e *Engine) Source(name string) (string, error) {
+	e.mu.RLock()
+	dirs := e.dirs
+	e.mu.RUnlock()
+
+	return source(dirs, name)
+}
+
+// source returns the path of the first file in dirs that overrides the named
+// template, or "" if there is none.
+func source(dirs []string, name string) (string, error) {
+	for _, dir := range dirs {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		_, err := os.Stat(p)
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, iofs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// parse reads and parses the named template from the first of dirs that has
+// it, or else from the embedded file system. Either way, the template can use
+// the helper functions in funcs.
+func parse(dirs []string, name string) (*template.Template, error) {
+	file, err := source(dirs, name)
 	if err != nil {
-		e.templates[name] = nil
-		return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		return nil, err
+	}
+
+	tmpl := template.New(path.Base(name)).Funcs(funcs)
+	if file == "" {
+		return tmpl.ParseFS(fs, name)
 	}
 
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Parse(string(text))
+}
+
+// lookup parses a template from the override directories or the embedded file
+// system and caches it in the engine's templates map for future use. The
+// write lock is held while parsing, so that concurrent lookups of the same
+// template parse it only once and SetDirs can't change the directories
+// underneath it. Failures aren't cached, so a fixed template can be retried.
+func (e *Engine) lookup(name string) (*template.Template, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	// Another goroutine may have parsed it while we waited for the lock.
+	if tmpl, ok := e.templates[name]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := parse(e.dirs, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+	}
+	e.templates[name] = tmpl
 	return tmpl, nil
 }
 
@@ -86,6 +245,24 @@ func (e *Engine) Lookup(name string) (*template.Template, error) {
 	return tmpl, nil
 }
 
+// Validate parses every template, so that mistakes in an override are reported
+// up front rather than partway through a commit. The parsed templates are
+// cached for rendering. It returns every error it finds.
+func (e *Engine) Validate() error {
+	names, err := Names()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range names {
+		if _, err := e.Lookup(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // RenderBytes returns a byte slice for the rendered template.
 func (e *Engine) RenderBytes(name string, data any) ([]byte, error) {
 	tmpl, err := e.Lookup(name)
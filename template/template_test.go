@@ -2,7 +2,10 @@ package template_test
 
 import (
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/ivy/git-auto-commit/template"
@@ -125,3 +128,237 @@ var _ = Describe("Engine", func() {
 		})
 	})
 })
+
+var _ = Describe("Overrides", func() {
+	var repoDir, userDir string
+
+	BeforeEach(func() {
+		repoDir = GinkgoT().TempDir()
+		userDir = GinkgoT().TempDir()
+	})
+
+	write := func(dir, name, text string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		Expect(os.WriteFile(p, []byte(text), 0o644)).To(Succeed())
+	}
+
+	It("prefers the first directory that has the template", func() {
+		write(userDir, "format/commit.tmpl", "user {{.}}")
+		write(repoDir, "format/commit.tmpl", "repo {{.}}")
+		write(userDir, "prompt/pr_title.tmpl", "user title")
+
+		engine := template.New(repoDir, userDir)
+
+		out, err := engine.RenderString("format/commit.tmpl", "format")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("repo format"))
+
+		out, err = engine.RenderString("prompt/pr_title.tmpl", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("user title"))
+
+		source, err := engine.Source("format/commit.tmpl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal(filepath.Join(repoDir, "format", "commit.tmpl")))
+	})
+
+	It("falls back to the embedded templates", func() {
+		engine := template.New(repoDir, userDir)
+
+		out, err := engine.RenderString("format/commit.tmpl", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("Capitalized, short"))
+
+		source, err := engine.Source("format/commit.tmpl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(BeEmpty())
+	})
+
+	It("forgets loaded templates when the directories change", func() {
+		write(repoDir, "format/commit.tmpl", "repo")
+		engine := template.New()
+
+		out, err := engine.RenderString("format/commit.tmpl", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("Capitalized, short"))
+
+		engine.SetDirs(repoDir)
+		out, err = engine.RenderString("format/commit.tmpl", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("repo"))
+	})
+
+	It("reports syntax errors in overrides", func() {
+		write(repoDir, "format/commit.tmpl", "{{.Broken")
+		engine := template.New(repoDir)
+
+		_, err := engine.RenderString("format/commit.tmpl", nil)
+		Expect(err).To(MatchError(ContainSubstring(`failed to parse template "format/commit.tmpl"`)))
+	})
+
+	It("finds the repository and user directories", func() {
+		GinkgoT().Setenv("XDG_CONFIG_HOME", userDir)
+
+		Expect(template.Dirs("/src/project")).To(Equal([]string{
+			filepath.Join("/src/project", ".git-auto-commit", "templates"),
+			filepath.Join(userDir, "git-auto-commit", "templates"),
+		}))
+		Expect(template.Dirs("")).To(Equal([]string{
+			filepath.Join(userDir, "git-auto-commit", "templates"),
+		}))
+	})
+})
+
+var _ = Describe("Cache", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	write := func(name, text string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+		Expect(os.WriteFile(p, []byte(text), 0o644)).To(Succeed())
+	}
+
+	It("parses each template once", func() {
+		engine := template.New()
+
+		first, err := engine.Lookup("format/commit.tmpl")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := engine.Lookup("format/commit.tmpl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+
+	It("retries templates that failed to parse", func() {
+		write("format/commit.tmpl", "{{.Broken")
+		engine := template.New(dir)
+
+		_, err := engine.Lookup("format/commit.tmpl")
+		Expect(err).To(HaveOccurred())
+
+		write("format/commit.tmpl", "fixed")
+		out, err := engine.RenderString("format/commit.tmpl", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("fixed"))
+	})
+
+	It("validates every embedded template", func() {
+		Expect(template.New().Validate()).To(Succeed())
+	})
+
+	It("reports every broken override when validating", func() {
+		write("format/commit.tmpl", "{{.Broken")
+		write("prompt/commit.tmpl", "{{end}}")
+		engine := template.New(dir)
+
+		err := engine.Validate()
+		Expect(err).To(MatchError(ContainSubstring(`"format/commit.tmpl"`)))
+		Expect(err).To(MatchError(ContainSubstring(`"prompt/commit.tmpl"`)))
+	})
+
+	It("renders concurrently", func() {
+		write("format/commit.tmpl", "repo {{.}}")
+		engine := template.New()
+
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for range 50 {
+					if i == 0 {
+						engine.SetDirs(dir)
+						engine.SetDirs()
+						continue
+					}
+					out, err := engine.RenderString("format/commit.tmpl", "x")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(out).To(Or(Equal("repo x"), HavePrefix("Capitalized, short")))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+})
+
+var _ = Describe("Eject", func() {
+	It("copies the embedded template without overwriting an existing one", func() {
+		dir := GinkgoT().TempDir()
+
+		dest, err := template.Eject("prompt/commit.tmpl", dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dest).To(Equal(filepath.Join(dir, "prompt", "commit.tmpl")))
+
+		ejected, err := os.ReadFile(dest)
+		Expect(err).NotTo(HaveOccurred())
+		original, err := template.Default("prompt/commit.tmpl")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ejected).To(Equal(original))
+
+		_, err = template.Eject("prompt/commit.tmpl", dir)
+		Expect(err).To(MatchError(os.ErrExist))
+	})
+
+	It("rejects unknown templates", func() {
+		_, err := template.Eject("prompt/nope.tmpl", GinkgoT().TempDir())
+		Expect(err).To(MatchError(ContainSubstring(`no default template named "prompt/nope.tmpl"`)))
+	})
+
+	It("lists the embedded templates", func() {
+		names, err := template.Names()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(ContainElements("format/commit.tmpl", "prompt/commit.tmpl"))
+	})
+})
+
+var _ = Describe("Helper functions", func() {
+	render := func(text string, data any) (string, error) {
+		dir := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "prompt"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "prompt", "test.tmpl"), []byte(text), 0o644)).To(Succeed())
+		return template.New(dir).RenderString("prompt/test.tmpl", data)
+	}
+
+	DescribeTable("render as expected",
+		func(text string, data any, expected string) {
+			out, err := render(text, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(Equal(expected))
+		},
+		Entry("truncate", `{{. | truncate 2}}`, "line one\nline two\n", "line one"),
+		Entry("indent", `{{indent 2 .}}`, "a\n\nb", "  a\n\n  b"),
+		Entry("wrap", `{{wrap 10 .}}`, "one two three four", "one two\nthree four"),
+		Entry("join", `{{join ", " .}}`, []string{"a", "b"}, "a, b"),
+		Entry("default", `{{default "none" .}}`, "", "none"),
+		Entry("contains", `{{if contains "fix" .}}yes{{end}}`, "a fix", "yes"),
+		Entry("hasSuffix", `{{if hasSuffix ".go" .}}go{{end}}`, "main.go", "go"),
+		Entry("regexReplace", `{{regexReplace "([A-Z]+)-(\\d+)" "$1 #$2" .}}`, "See ABC-12", "See ABC #12"),
+		Entry("extStats",
+			`{{range extStats .}}{{.Ext}}: {{.Files}} files +{{.Added}} -{{.Deleted}}; {{end}}`,
+			"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n-a\n+b\n+c\n"+
+				"diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1 +1 @@\n-a\n+b\n"+
+				"diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-a\n+b\n",
+			"go: 2 files +3 -2; md: 1 files +1 -1; "),
+		Entry("extStats of a summary", `{{len (extStats .)}}`, "not a diff", "0"),
+	)
+
+	It("reads the environment, but not credentials", func() {
+		GinkgoT().Setenv("TEAM_NAME", "platform")
+		GinkgoT().Setenv("OPENAI_API_KEY", "sk-secret")
+
+		out, err := render(`{{env "TEAM_NAME"}} {{env "OPENAI_API_KEY"}}`, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("platform "))
+	})
+
+	It("reports invalid regular expressions", func() {
+		_, err := render(`{{regexReplace "(" "" .}}`, "x")
+		Expect(err).To(MatchError(ContainSubstring("error parsing regexp")))
+	})
+})
@@ -0,0 +1,81 @@
+package git_auto_commit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ivy/git-auto-commit/template"
+	"github.com/ivy/git-auto-commit/util/git"
+	"github.com/ivy/git-auto-commit/util/log"
+)
+
+// TemplateDirs returns the directories whose templates override the built-in
+// ones, in order of priority: the current repository's, then the user's.
+// Outside of a repository, only the user's directory is used.
+func TemplateDirs() []string {
+	top, err := git.TopLevel()
+	if err != nil {
+		log.Debugw("not in a repository, skipping its templates",
+			"error", err)
+		top = ""
+	}
+	return template.Dirs(top)
+}
+
+// ListTemplates writes the name of every built-in template to w, along with
+// the file that overrides it, if any.
+func ListTemplates(w io.Writer) error {
+	names, err := template.Names()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		source, err := template.Source(name)
+		if err != nil {
+			return err
+		}
+		if source == "" {
+			source = "(built in)"
+		}
+		fmt.Fprintf(w, "%-28s %s\n", name, source)
+	}
+	return nil
+}
+
+// EjectTemplates copies the named built-in templates into the repository's
+// template directory, so that they can be edited to override the defaults,
+// and writes the path of each copy to w.
+func EjectTemplates(w io.Writer, names ...string) error {
+	known, err := template.Names()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("name a template to eject: %s", strings.Join(known, ", "))
+	}
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown template %q (expected one of %s)", name, strings.Join(known, ", "))
+		}
+	}
+
+	top, err := git.TopLevel()
+	if err != nil || top == "" {
+		return errors.New("templates can only be ejected inside a repository")
+	}
+
+	for _, name := range names {
+		dest, err := template.Eject(name, template.RepoDir(top))
+		if err != nil {
+			log.Errorw("failed to eject template",
+				"name", name,
+				"error", err)
+			return err
+		}
+		fmt.Fprintf(w, "Ejected %s to %s\n", name, dest)
+	}
+	return nil
+}
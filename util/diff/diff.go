@@ -0,0 +1,274 @@
+// Package diff parses unified diffs in the format produced by `git diff` into
+// files and hunks, so that they can be inspected and recombined into smaller
+// patches. Parsing is lossless: rendering the parsed files reproduces the
+// input byte for byte.
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches a hunk's "@@ -a,b +c,d @@" line. The line counts are
+// optional and default to 1.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// File is the part of a diff that describes changes to a single file.
+type File struct {
+	// Header holds the lines before the first hunk, starting with the
+	// "diff --git" line. Each line keeps its trailing newline.
+	Header []string
+
+	// Hunks are the file's hunks, in order. Binary files and changes that
+	// only affect metadata (such as the file mode) have none.
+	Hunks []*Hunk
+
+	// OldPath and NewPath are the file's paths before and after the change,
+	// without the "a/" and "b/" prefixes. A created file has an OldPath of
+	// "/dev/null" and a deleted file has a NewPath of "/dev/null".
+	OldPath string
+	NewPath string
+}
+
+// Hunk is a single "@@" section of a file's diff.
+type Hunk struct {
+	// Lines holds the hunk's lines, starting with the "@@" header. Each line
+	// keeps its trailing newline.
+	Lines []string
+}
+
+// Parse splits a diff produced by `git diff` into its files. It returns an
+// error if the input contains anything before the first "diff --git" line.
+func Parse(text string) ([]*File, error) {
+	var (
+		files []*File
+		file  *File
+		hunk  *Hunk
+	)
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = &File{Header: []string{line}}
+			hunk = nil
+			files = append(files, file)
+			continue
+		case file == nil:
+			return nil, fmt.Errorf("unexpected line before the first file: %q", strings.TrimRight(line, "\n"))
+		case strings.HasPrefix(line, "@@"):
+			hunk = &Hunk{Lines: []string{line}}
+			file.Hunks = append(file.Hunks, hunk)
+			continue
+		}
+
+		if hunk != nil {
+			hunk.Lines = append(hunk.Lines, line)
+			continue
+		}
+
+		file.Header = append(file.Header, line)
+		if path, ok := strings.CutPrefix(line, "--- "); ok {
+			file.OldPath = parsePath(path)
+		} else if path, ok := strings.CutPrefix(line, "+++ "); ok {
+			file.NewPath = parsePath(path)
+		}
+	}
+
+	// Files without "---" and "+++" lines (binary files, mode changes, and
+	// empty files) only name their paths in the "diff --git" line.
+	for _, f := range files {
+		if f.OldPath == "" || f.NewPath == "" {
+			oldPath, newPath := parseGitLine(f.Header[0])
+			if f.OldPath == "" {
+				f.OldPath = oldPath
+			}
+			if f.NewPath == "" {
+				f.NewPath = newPath
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// parsePath returns the path named in a "---" or "+++" line.
+func parsePath(s string) string {
+	s = strings.TrimRight(s, "\n")
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	// Trailing tabs separate the path from an optional timestamp.
+	s, _, _ = strings.Cut(s, "\t")
+	return stripPrefix(s)
+}
+
+// parseGitLine returns the paths named in a "diff --git a/x b/x" line. Paths
+// containing " b/" are ambiguous there; Parse prefers the "---" and "+++"
+// lines when they exist.
+func parseGitLine(line string) (string, string) {
+	rest := strings.TrimRight(strings.TrimPrefix(line, "diff --git "), "\n")
+	if strings.HasPrefix(rest, `"`) {
+		var oldPath, newPath string
+		if _, err := fmt.Sscanf(rest, "%q %q", &oldPath, &newPath); err == nil {
+			return stripPrefix(oldPath), stripPrefix(newPath)
+		}
+	}
+	oldPath, newPath, _ := strings.Cut(rest, " b/")
+	return stripPrefix(oldPath), newPath
+}
+
+// stripPrefix removes the "a/" or "b/" prefix Git adds to paths.
+func stripPrefix(path string) string {
+	if p, ok := strings.CutPrefix(path, "a/"); ok {
+		return p
+	}
+	if p, ok := strings.CutPrefix(path, "b/"); ok {
+		return p
+	}
+	return path
+}
+
+// Path returns the file's path after the change, or before it if the file
+// was deleted.
+func (f *File) Path() string {
+	if f.NewPath == "/dev/null" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// IsNew reports whether the file was created.
+func (f *File) IsNew() bool {
+	return f.OldPath == "/dev/null" || f.hasHeader("new file mode ")
+}
+
+// IsDeleted reports whether the file was deleted.
+func (f *File) IsDeleted() bool {
+	return f.NewPath == "/dev/null" || f.hasHeader("deleted file mode ")
+}
+
+// IsBinary reports whether the file's changes are binary.
+func (f *File) IsBinary() bool {
+	return f.hasHeader("Binary files ") || f.hasHeader("GIT binary patch")
+}
+
+// hasHeader reports whether any header line starts with prefix.
+func (f *File) hasHeader(prefix string) bool {
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stat returns the number of added and deleted lines across all hunks.
+func (f *File) Stat() (added, deleted int) {
+	for _, h := range f.Hunks {
+		a, d := h.Stat()
+		added += a
+		deleted += d
+	}
+	return added, deleted
+}
+
+// String renders the file's diff exactly as it was parsed.
+func (f *File) String() string {
+	return f.Patch(f.Hunks...)
+}
+
+// Patch renders the file's header followed by only the given hunks, producing
+// a patch that `git apply` accepts. Hunks must be given in their original
+// order.
+func (f *File) Patch(hunks ...*Hunk) string {
+	var b strings.Builder
+	for _, line := range f.Header {
+		b.WriteString(line)
+	}
+	for _, h := range hunks {
+		b.WriteString(h.String())
+	}
+	return b.String()
+}
+
+// Header returns the hunk's "@@" line without its trailing newline.
+func (h *Hunk) Header() string {
+	return strings.TrimRight(h.Lines[0], "\n")
+}
+
+// Range returns the start line and line count of the hunk on each side, as
+// given in its header. It returns zeros if the header is malformed.
+func (h *Hunk) Range() (oldStart, oldLines, newStart, newLines int) {
+	m := hunkHeader.FindStringSubmatch(h.Header())
+	if m == nil {
+		return 0, 0, 0, 0
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	oldStart, _ = strconv.Atoi(m[1])
+	newStart, _ = strconv.Atoi(m[3])
+	return oldStart, count(m[2]), newStart, count(m[4])
+}
+
+// Moved returns a copy of the hunk whose header starts at the given lines.
+// It's used to apply a hunk to a file where other hunks have already been
+// applied, or skipped, before it.
+func (h *Hunk) Moved(oldStart, newStart int) *Hunk {
+	m := hunkHeader.FindStringSubmatch(h.Header())
+	if m == nil {
+		return h
+	}
+	header := fmt.Sprintf("@@ -%d", oldStart)
+	if m[2] != "" {
+		header += "," + m[2]
+	}
+	header += fmt.Sprintf(" +%d", newStart)
+	if m[4] != "" {
+		header += "," + m[4]
+	}
+	header += " @@" + m[5] + "\n"
+
+	lines := append([]string{header}, h.Lines[1:]...)
+	return &Hunk{Lines: lines}
+}
+
+// Stat returns the number of added and deleted lines in the hunk.
+func (h *Hunk) Stat() (added, deleted int) {
+	for _, line := range h.Lines[1:] {
+		switch line[0] {
+		case '+':
+			added++
+		case '-':
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
+// String renders the hunk exactly as it was parsed.
+func (h *Hunk) String() string {
+	return strings.Join(h.Lines, "")
+}
+
+// String renders files back into a single diff.
+func String(files []*File) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package diff_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/util/diff"
+)
+
+func TestDiff(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diff Suite")
+}
+
+const sample = `diff --git a/main.go b/main.go
+index 1c99002..ac420dc 100644
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,6 @@
+ package main
+
++import "fmt"
+ func main() {
+ }
+
+@@ -31,6 +32,7 @@ func helper() {
+ 	a := 1
+-	b := 2
++	b := 3
++	c := 4
+ }
+\ No newline at end of file
+diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..3b18e51
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello world
+diff --git a/logo.png b/logo.png
+index 5d41402..7d79304 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+`
+
+var _ = Describe("Parse", func() {
+	It("round-trips the input exactly", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.String(files)).To(Equal(sample))
+	})
+
+	It("splits files and hunks", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(4))
+
+		Expect(files[0].Path()).To(Equal("main.go"))
+		Expect(files[0].Hunks).To(HaveLen(2))
+		Expect(files[0].Hunks[1].Header()).To(Equal("@@ -31,6 +32,7 @@ func helper() {"))
+		Expect(files[0].Hunks[1].Lines).To(ContainElement("\\ No newline at end of file\n"))
+
+		added, deleted := files[0].Stat()
+		Expect(added).To(Equal(3))
+		Expect(deleted).To(Equal(1))
+	})
+
+	It("recognizes new, binary, and metadata-only files", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(files[1].Path()).To(Equal("new.txt"))
+		Expect(files[1].IsNew()).To(BeTrue())
+		Expect(files[1].IsDeleted()).To(BeFalse())
+
+		Expect(files[2].Path()).To(Equal("logo.png"))
+		Expect(files[2].IsBinary()).To(BeTrue())
+		Expect(files[2].Hunks).To(BeEmpty())
+
+		Expect(files[3].Path()).To(Equal("script.sh"))
+		Expect(files[3].Hunks).To(BeEmpty())
+	})
+
+	It("renders a patch with a subset of hunks", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+
+		patch := files[0].Patch(files[0].Hunks[1])
+		Expect(patch).To(HavePrefix("diff --git a/main.go b/main.go\n"))
+		Expect(patch).To(ContainSubstring("@@ -31,6 +32,7 @@"))
+		Expect(patch).NotTo(ContainSubstring("@@ -1,5 +1,6 @@"))
+	})
+
+	It("reads deleted files' paths from the old side", func() {
+		files, err := diff.Parse("diff --git a/old.txt b/old.txt\n" +
+			"deleted file mode 100644\n" +
+			"--- a/old.txt\n" +
+			"+++ /dev/null\n" +
+			"@@ -1 +0,0 @@\n" +
+			"-bye\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files[0].IsDeleted()).To(BeTrue())
+		Expect(files[0].Path()).To(Equal("old.txt"))
+	})
+
+	It("returns nothing for an empty diff", func() {
+		files, err := diff.Parse("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+
+	It("rejects text before the first file", func() {
+		_, err := diff.Parse("hello\ndiff --git a/x b/x\n")
+		Expect(err).To(MatchError(ContainSubstring("before the first file")))
+	})
+})
+
+var _ = Describe("Hunk", func() {
+	It("reads its range, defaulting counts to 1", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+
+		oldStart, oldLines, newStart, newLines := files[0].Hunks[1].Range()
+		Expect([]int{oldStart, oldLines, newStart, newLines}).To(Equal([]int{31, 6, 32, 7}))
+
+		oldStart, oldLines, newStart, newLines = files[1].Hunks[0].Range()
+		Expect([]int{oldStart, oldLines, newStart, newLines}).To(Equal([]int{0, 0, 1, 1}))
+	})
+
+	It("moves to new start lines, keeping the rest of the header", func() {
+		files, err := diff.Parse(sample)
+		Expect(err).NotTo(HaveOccurred())
+
+		moved := files[0].Hunks[1].Moved(30, 30)
+		Expect(moved.Header()).To(Equal("@@ -30,6 +30,7 @@ func helper() {"))
+		Expect(moved.Lines[1:]).To(Equal(files[0].Hunks[1].Lines[1:]))
+		Expect(files[0].Hunks[1].Header()).To(Equal("@@ -31,6 +32,7 @@ func helper() {"))
+	})
+})
@@ -20,6 +20,7 @@ type Cmd interface {
 	SetStdin(io.Reader)
 	SetStdout(io.Writer)
 	SetStderr(io.Writer)
+	SetEnv([]string)
 	Output() ([]byte, error)
 	Run() error
 }
@@ -5,12 +5,18 @@ import "io"
 // MockCmd is a stubbed command that you can use in tests. You can customize the
 // behavior by setting fields or implementing side effects.
 type MockCmd struct {
+	// Args records the command name and arguments. NewMockCmd leaves it empty;
+	// tests that override exec.Command can fill it in to verify invocations.
+	Args []string
+
 	// Simulated input for SetStdin().
 	Stdin io.Reader
 	// Simulated output for SetStdout().
 	Stdout io.Writer
 	// Simulated output for SetStderr().
 	Stderr io.Writer
+	// Environment passed to SetEnv().
+	Env []string
 
 	// Simulated output for Output().
 	MockOutput []byte
@@ -23,6 +29,7 @@ type MockCmd struct {
 	SetStdinCalled  bool
 	SetStdoutCalled bool
 	SetStderrCalled bool
+	SetEnvCalled    bool
 	RunCalled       bool
 }
 
@@ -61,6 +68,11 @@ func (m *MockCmd) SetStderr(stderr io.Writer) {
 	m.Stderr = stderr
 }
 
+func (m *MockCmd) SetEnv(env []string) {
+	m.SetEnvCalled = true
+	m.Env = env
+}
+
 func (m *MockCmd) Run() error {
 	m.RunCalled = true
 	return m.MockError
@@ -2,6 +2,7 @@ package exec
 
 import (
 	"io"
+	"os"
 	"os/exec"
 
 	"github.com/ivy/git-auto-commit/util/log"
@@ -39,6 +40,12 @@ func (r *RealCmd) SetStderr(stderr io.Writer) {
 	r.cmd.Stderr = stderr
 }
 
+// SetEnv adds "KEY=value" entries to the environment the command inherits
+// from the current process.
+func (r *RealCmd) SetEnv(env []string) {
+	r.cmd.Env = append(os.Environ(), env...)
+}
+
 func (r *RealCmd) Run() error {
 	log.Debugw("running command", "command", r.cmd.Path, "args", r.cmd.Args)
 	return r.cmd.Run()
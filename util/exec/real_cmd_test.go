@@ -28,3 +28,13 @@ var _ = Describe("RealCmd", func() {
 		})
 	})
 })
+
+var _ = Describe("RealCmd SetEnv", func() {
+	It("adds variables to the inherited environment", func() {
+		cmd := exec.NewRealCmd("sh", "-c", `echo "$GIT_AUTO_COMMIT_TEST_VAR"`)
+		cmd.SetEnv([]string{"GIT_AUTO_COMMIT_TEST_VAR=hello"})
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(string(output))).To(Equal("hello"))
+	})
+})
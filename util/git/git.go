@@ -1,33 +1,209 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	stdexec "os/exec"
 	"strings"
 
 	"github.com/ivy/git-auto-commit/util/exec"
 )
 
-// Status returns the output of `git status` command. It returns the status as a
+// Status returns the output of `git status` command, without the hints about
+// what to run next, as `git commit` shows it. It returns the status as a
 // string and an error if the command fails.
 func Status() (string, error) {
-	cmd := exec.Command("git", "status")
+	cmd := exec.Command("git", "-c", "advice.statusHints=false", "status")
 	out, err := cmd.Output()
 	return string(out), err
 }
 
+// ConfigValue returns the value of a Git configuration key, such as
+// "core.commentChar", or "" if it isn't set. Options such as
+// "--type=bool-or-int" are passed to `git config`. It returns an error if the
+// command fails for any other reason.
+func ConfigValue(key string, options ...string) (string, error) {
+	args := append(append([]string{"config", "--get"}, options...), key)
+	out, err := exec.Command("git", args...).Output()
+	var exitErr *stdexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// Editor returns the editor Git uses for commit messages, from $GIT_EDITOR,
+// core.editor, $VISUAL, or $EDITOR, as a shell command. It returns an error if
+// there's none, such as on a dumb terminal.
+func Editor() (string, error) {
+	cmd := exec.Command("git", "var", "GIT_EDITOR")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
 // Diff returns the output of `git diff` command. If cached is true, it returns
-// the output of `git diff --cached`. It returns the diff as a string and an
-// error if the command fails.
-func Diff(cached bool) (string, error) {
+// the output of `git diff --cached`. Any pathspecs, such as those from
+// ExcludePathspecs, limit the diff to matching paths. It returns the diff as a
+// string and an error if the command fails.
+func Diff(cached bool, pathspecs ...string) (string, error) {
 	args := []string{"diff"}
 	if cached {
 		args = append(args, "--cached")
 	}
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command("git", withPathspecs(args, pathspecs)...)
 	out, err := cmd.Output()
 	return string(out), err
 }
 
+// DiffCachedAgainst returns the output of `git diff --cached <rev>`, the
+// changes between the given revision and the index, limited to any
+// pathspecs. It returns the diff as a string and an error if the command
+// fails.
+func DiffCachedAgainst(rev string, pathspecs ...string) (string, error) {
+	cmd := exec.Command("git", withPathspecs([]string{"diff", "--cached", rev}, pathspecs)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// DiffRevisions returns the output of `git diff <revisions>`, for any
+// revision range `git diff` accepts, such as "main...HEAD" or "v1.0 v1.1",
+// limited to any pathspecs. It returns an error if the command fails.
+func DiffRevisions(revisions string, pathspecs ...string) (string, error) {
+	args := append([]string{"diff"}, strings.Fields(revisions)...)
+	cmd := exec.Command("git", withPathspecs(args, pathspecs)...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// RevisionFiles returns the paths of files that differ in a revision range,
+// like DiffRevisions, limited to any pathspecs. It returns an error if the
+// command fails.
+func RevisionFiles(revisions string, pathspecs ...string) ([]string, error) {
+	args := append([]string{"diff", "--name-only"}, strings.Fields(revisions)...)
+	cmd := exec.Command("git", withPathspecs(args, pathspecs)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return lines(string(out)), nil
+}
+
+// StagedFiles returns the paths of files that differ between base and the
+// index, limited to any pathspecs. An empty base compares against HEAD. It
+// returns an error if the command fails.
+func StagedFiles(base string, pathspecs ...string) ([]string, error) {
+	args := []string{"diff", "--cached", "--name-only"}
+	if base != "" {
+		args = append(args, base)
+	}
+	cmd := exec.Command("git", withPathspecs(args, pathspecs)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return lines(string(out)), nil
+}
+
+// withPathspecs appends pathspecs to args after a "--" separator, if there
+// are any.
+func withPathspecs(args, pathspecs []string) []string {
+	if len(pathspecs) == 0 {
+		return args
+	}
+	return append(append(args, "--"), pathspecs...)
+}
+
+// lines splits command output into its non-empty lines.
+func lines(out string) []string {
+	var result []string
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// ExcludePathspecs converts gitignore-style patterns into pathspecs. The
+// include pathspecs match the paths the patterns match; the exclude
+// pathspecs match every other path in the repository. Negated ("!") patterns
+// aren't supported and are skipped.
+func ExcludePathspecs(patterns []string) (include, exclude []string) {
+	for _, pattern := range patterns {
+		for _, glob := range ignoreGlobs(pattern) {
+			include = append(include, ":(top,glob)"+glob)
+			exclude = append(exclude, ":(top,exclude,glob)"+glob)
+		}
+	}
+	if len(exclude) > 0 {
+		exclude = append([]string{":(top)"}, exclude...)
+	}
+	return include, exclude
+}
+
+// ignoreGlobs returns the pathspec globs equivalent to a gitignore pattern.
+func ignoreGlobs(pattern string) []string {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") || strings.HasPrefix(pattern, "!") {
+		return nil
+	}
+
+	// A trailing slash only matches directories.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	// Patterns with a slash anywhere but the end are relative to the top of
+	// the repository; others match at any depth.
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else if !strings.HasPrefix(pattern, "**/") {
+		pattern = "**/" + pattern
+	}
+
+	if dirOnly {
+		return []string{pattern + "/**"}
+	}
+	return []string{pattern, pattern + "/**"}
+}
+
+// TopLevel returns the absolute path of the top of the working tree. It
+// returns an error if the command fails.
+func TopLevel() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// HooksDir returns the directory Git runs hooks from, which honors
+// core.hooksPath. It returns an error if the command fails.
+func HooksDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// ParentOrEmptyTree returns "<rev>^" if the given revision has a parent, or
+// the ID of the empty tree if it's a root commit, so that the result can
+// always be diffed against.
+func ParentOrEmptyTree(rev string) (string, error) {
+	parent := rev + "^"
+	if _, err := exec.Command("git", "rev-parse", "--verify", "--quiet", parent).Output(); err == nil {
+		return parent, nil
+	}
+
+	cmd := exec.Command("git", "hash-object", "-t", "tree", "/dev/null")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// Message returns the full commit message of the given revision. It returns
+// an error if the command fails.
+func Message(rev string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", rev)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
 // DefaultBranch returns the name of the default branch. It returns the branch
 // name as a string and an error if the command fails.
 func DefaultBranch() (string, error) {
@@ -63,3 +239,212 @@ func Log() (string, error) {
 	out, err := cmd.Output()
 	return string(out), err
 }
+
+// Commit describes a commit object.
+type Commit struct {
+	// ID is the full commit hash.
+	ID string
+	// Tree is the hash of the commit's tree.
+	Tree string
+	// Parents are the hashes of the commit's parents.
+	Parents []string
+	// AuthorName, AuthorEmail, and AuthorDate identify the commit's author.
+	// AuthorDate is in Git's raw "<unix seconds> <offset>" format.
+	AuthorName  string
+	AuthorEmail string
+	AuthorDate  string
+	// Message is the full commit message, without surrounding whitespace.
+	Message string
+	// RawMessage is the message exactly as it's stored, for writing the
+	// commit again unchanged.
+	RawMessage string
+}
+
+// AuthorEnv returns environment variables that make `git commit-tree` record
+// the same author as c.
+func (c *Commit) AuthorEnv() []string {
+	return []string{
+		"GIT_AUTHOR_NAME=" + c.AuthorName,
+		"GIT_AUTHOR_EMAIL=" + c.AuthorEmail,
+		"GIT_AUTHOR_DATE=" + c.AuthorDate,
+	}
+}
+
+// commitFormat is the `git log` format read by parseCommit.
+const commitFormat = "%H%x00%T%x00%P%x00%an%x00%ae%x00%ad%x00%B"
+
+// parseCommit parses a commit printed with commitFormat.
+func parseCommit(out string) (*Commit, bool) {
+	fields := strings.SplitN(out, "\x00", 7)
+	if len(fields) != 7 {
+		return nil, false
+	}
+	return &Commit{
+		ID:          fields[0],
+		Tree:        fields[1],
+		Parents:     strings.Fields(fields[2]),
+		AuthorName:  fields[3],
+		AuthorEmail: fields[4],
+		AuthorDate:  fields[5],
+		Message:     strings.TrimSpace(fields[6]),
+		RawMessage:  fields[6],
+	}, true
+}
+
+// ReadCommit returns the commit identified by rev. It returns an error if the
+// command fails or rev isn't a commit.
+func ReadCommit(rev string) (*Commit, error) {
+	cmd := exec.Command(
+		"git", "log", "-1", "--date=raw",
+		"--format="+commitFormat,
+		rev, "--",
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// The format is followed by a newline, which isn't part of the message.
+	commit, ok := parseCommit(strings.TrimSuffix(string(out), "\n"))
+	if !ok {
+		return nil, fmt.Errorf("unexpected output reading commit %q", rev)
+	}
+	return commit, nil
+}
+
+// RecentCommits returns up to n of the most recent non-merge commits on HEAD,
+// newest first. It returns an error if the command fails, such as in a
+// repository without commits.
+func RecentCommits(n int) ([]*Commit, error) {
+	cmd := exec.Command(
+		"git", "log", "--no-merges", "--date=raw",
+		fmt.Sprintf("--max-count=%d", n),
+		"--format="+commitFormat+"%x1e",
+		"HEAD", "--",
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		commit, ok := parseCommit(record)
+		if !ok {
+			return nil, errors.New("unexpected output reading recent commits")
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// RevList returns the commit hashes printed by `git rev-list <args>`. It
+// returns an error if the command fails.
+func RevList(args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"rev-list"}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// CommitDiff returns the patch introduced by the given commit, as shown by
+// `git show`, limited to any pathspecs. It returns an error if the command
+// fails.
+func CommitDiff(rev string, pathspecs ...string) (string, error) {
+	args := append([]string{"show", "--format=", "--patch", rev, "--"}, pathspecs...)
+	cmd := exec.Command("git", args...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// CommitFiles returns the paths of files changed by the given commit, limited
+// to any pathspecs. It returns an error if the command fails.
+func CommitFiles(rev string, pathspecs ...string) ([]string, error) {
+	args := append([]string{"show", "--format=", "--name-only", rev, "--"}, pathspecs...)
+	cmd := exec.Command("git", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return lines(string(out)), nil
+}
+
+// CommitTree creates a commit object for the given tree, parents, and message
+// with `git commit-tree`, and returns its hash. Extra environment variables,
+// such as those from Commit.AuthorEnv, are passed to the command.
+func CommitTree(tree string, parents []string, message string, env []string) (string, error) {
+	args := []string{"commit-tree", tree}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	args = append(args, "-F", "-")
+
+	cmd := exec.Command("git", args...)
+	cmd.SetStdin(strings.NewReader(message))
+	cmd.SetEnv(env)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// UpdateRef points ref at newValue with `git update-ref`, provided it still
+// points at oldValue. The reason is recorded in the reflog.
+func UpdateRef(ref, newValue, oldValue, reason string) error {
+	cmd := exec.Command("git", "update-ref", "-m", reason, ref, newValue, oldValue)
+	_, err := cmd.Output()
+	return err
+}
+
+// StagedPatch returns the staged changes as a patch that `git apply` can
+// reapply exactly: binary changes are included in full and renames are shown
+// as a deletion and an addition. It returns an error if the command fails.
+func StagedPatch() (string, error) {
+	cmd := exec.Command(
+		"git", "diff", "--cached", "--binary", "--no-renames", "--no-color", "--no-ext-diff",
+	)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// RevParse returns the object ID that rev resolves to. It returns an error if
+// the command fails or rev doesn't exist.
+func RevParse(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// WriteTree writes the index to a tree object and returns its ID.
+func WriteTree() (string, error) {
+	cmd := exec.Command("git", "write-tree")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// ReadTree replaces the index with the given tree, leaving the working tree
+// untouched.
+func ReadTree(treeish string) error {
+	_, err := exec.Command("git", "read-tree", treeish).Output()
+	return err
+}
+
+// ApplyCached applies patch to the index with `git apply --cached`, leaving
+// the working tree untouched.
+func ApplyCached(patch string) error {
+	cmd := exec.Command("git", "apply", "--cached", "-")
+	cmd.SetStdin(strings.NewReader(patch))
+	_, err := cmd.Output()
+	return err
+}
+
+// ResetSoft points the current branch at rev with `git reset --soft`, leaving
+// the index and working tree untouched.
+func ResetSoft(rev string) error {
+	_, err := exec.Command("git", "reset", "--soft", rev).Output()
+	return err
+}
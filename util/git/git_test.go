@@ -2,6 +2,7 @@ package git_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -174,3 +175,311 @@ var _ = Describe("Diff", func() {
 		})
 	})
 })
+
+var _ = Describe("DiffCachedAgainst", func() {
+	var (
+		originalCommand func(name string, args ...string) exec.Cmd
+		calls           [][]string
+	)
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+		calls = nil
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			calls = append(calls, append([]string{name}, args...))
+			return exec.NewMockCmd([]byte("diff output"), nil)
+		})
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("diffs the index against the given revision", func() {
+		diff, err := git.DiffCachedAgainst("HEAD^")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(Equal("diff output"))
+		Expect(calls).To(Equal([][]string{{"git", "diff", "--cached", "HEAD^"}}))
+	})
+})
+
+var _ = Describe("ParentOrEmptyTree", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("returns the parent revision when it exists", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			return exec.NewMockCmd([]byte("abc123\n"), nil)
+		})
+
+		rev, err := git.ParentOrEmptyTree("HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rev).To(Equal("HEAD^"))
+	})
+
+	It("returns the empty tree for a root commit", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			if args[0] == "rev-parse" {
+				return exec.NewMockCmd(nil, fmt.Errorf("exit status 1"))
+			}
+			return exec.NewMockCmd([]byte("4b825dc642cb6eb9a060e54bf8d69288fbee4904\n"), nil)
+		})
+
+		rev, err := git.ParentOrEmptyTree("HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rev).To(Equal("4b825dc642cb6eb9a060e54bf8d69288fbee4904"))
+	})
+})
+
+var _ = Describe("Message", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("returns the trimmed commit message", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			Expect(args).To(Equal([]string{"log", "-1", "--format=%B", "HEAD"}))
+			return exec.NewMockCmd([]byte("Add greeting\n\nSay hello.\n\n"), nil)
+		})
+
+		message, err := git.Message("HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(Equal("Add greeting\n\nSay hello."))
+	})
+})
+
+var _ = Describe("ReadCommit", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("parses the commit's metadata and message", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			return exec.NewMockCmd([]byte(
+				"c0ffee\x00beef\x00p1 p2\x00Ada\x00ada@example.com\x001700000000 +0100\x00wip\n\nmore\n\n",
+			), nil)
+		})
+
+		commit, err := git.ReadCommit("HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commit).To(Equal(&git.Commit{
+			ID:          "c0ffee",
+			Tree:        "beef",
+			Parents:     []string{"p1", "p2"},
+			AuthorName:  "Ada",
+			AuthorEmail: "ada@example.com",
+			AuthorDate:  "1700000000 +0100",
+			Message:     "wip\n\nmore",
+			RawMessage:  "wip\n\nmore\n",
+		}))
+		Expect(commit.AuthorEnv()).To(ContainElement("GIT_AUTHOR_DATE=1700000000 +0100"))
+	})
+
+	It("returns no parents for a root commit", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			return exec.NewMockCmd([]byte("c0ffee\x00beef\x00\x00Ada\x00ada@example.com\x001700000000 +0000\x00init\n"), nil)
+		})
+
+		commit, err := git.ReadCommit("HEAD")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commit.Parents).To(BeEmpty())
+	})
+})
+
+var _ = Describe("RecentCommits", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("reads non-merge commits newest first", func() {
+		var args []string
+		exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+			args = arg
+			return exec.NewMockCmd([]byte(
+				"c2\x00t2\x00c1\x00Ada\x00ada@example.com\x001700000100 +0000\x00Add logging\n\nWith levels.\n\x1e\n"+
+					"c1\x00t1\x00\x00Bob\x00bob@example.com\x001700000000 +0000\x00Initial commit\n\x1e\n",
+			), nil)
+		})
+
+		commits, err := git.RecentCommits(2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(args).To(ContainElements("--no-merges", "--max-count=2"))
+		Expect(commits).To(HaveLen(2))
+		Expect(commits[0].ID).To(Equal("c2"))
+		Expect(commits[0].Message).To(Equal("Add logging\n\nWith levels."))
+		Expect(commits[1].AuthorName).To(Equal("Bob"))
+		Expect(commits[1].Parents).To(BeEmpty())
+	})
+
+	It("returns nothing for empty output", func() {
+		exec.SetCommand(func(name string, arg ...string) exec.Cmd {
+			return exec.NewMockCmd([]byte(""), nil)
+		})
+
+		commits, err := git.RecentCommits(10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commits).To(BeEmpty())
+	})
+})
+
+var _ = Describe("CommitTree", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("creates a commit with the given parents, message, and environment", func() {
+		mockCmd := exec.NewMockCmd([]byte("newsha\n"), nil).(*exec.MockCmd)
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			Expect(args).To(Equal([]string{"commit-tree", "beef", "-p", "p1", "-F", "-"}))
+			return mockCmd
+		})
+
+		id, err := git.CommitTree("beef", []string{"p1"}, "Add greeting", []string{"GIT_AUTHOR_NAME=Ada"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("newsha"))
+		Expect(mockCmd.Env).To(Equal([]string{"GIT_AUTHOR_NAME=Ada"}))
+		Expect(mockCmd.Stdin).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("StagedPatch", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("asks for a patch that can be reapplied", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			Expect(args).To(ContainElements("--cached", "--binary", "--no-renames"))
+			return exec.NewMockCmd([]byte("diff output"), nil)
+		})
+
+		patch, err := git.StagedPatch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch).To(Equal("diff output"))
+	})
+})
+
+var _ = Describe("ApplyCached", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("applies the patch from stdin to the index", func() {
+		mockCmd := exec.NewMockCmd(nil, nil).(*exec.MockCmd)
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			Expect(args).To(Equal([]string{"apply", "--cached", "-"}))
+			return mockCmd
+		})
+
+		Expect(git.ApplyCached("diff --git a/x b/x\n")).To(Succeed())
+		Expect(mockCmd.Stdin).NotTo(BeNil())
+	})
+
+	It("returns the command's error", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			return exec.NewMockCmd(nil, fmt.Errorf("exit status 1"))
+		})
+
+		Expect(git.ApplyCached("bad")).To(MatchError("exit status 1"))
+	})
+})
+
+var _ = Describe("ExcludePathspecs", func() {
+	It("returns nothing without patterns", func() {
+		include, exclude := git.ExcludePathspecs(nil)
+		Expect(include).To(BeEmpty())
+		Expect(exclude).To(BeEmpty())
+	})
+
+	DescribeTable("converts gitignore patterns to globs",
+		func(pattern string, expected []string) {
+			include, exclude := git.ExcludePathspecs([]string{pattern})
+
+			var globs []string
+			for _, spec := range include {
+				globs = append(globs, strings.TrimPrefix(spec, ":(top,glob)"))
+			}
+			Expect(globs).To(Equal(expected))
+
+			if len(expected) > 0 {
+				Expect(exclude[0]).To(Equal(":(top)"))
+				Expect(exclude[1:]).To(HaveLen(len(expected)))
+				Expect(exclude[1]).To(Equal(":(top,exclude,glob)" + expected[0]))
+			}
+		},
+		Entry("names match at any depth", "go.sum", []string{"**/go.sum", "**/go.sum/**"}),
+		Entry("wildcards match at any depth", "*.min.js", []string{"**/*.min.js", "**/*.min.js/**"}),
+		Entry("trailing slashes match directories", "vendor/", []string{"**/vendor/**"}),
+		Entry("leading slashes anchor to the top", "/build", []string{"build", "build/**"}),
+		Entry("inner slashes anchor to the top", "web/dist", []string{"web/dist", "web/dist/**"}),
+		Entry("comments are skipped", "# secrets", nil),
+		Entry("negations are skipped", "!keep.lock", nil),
+	)
+})
+
+var _ = Describe("StagedFiles", func() {
+	var originalCommand func(name string, args ...string) exec.Cmd
+
+	BeforeEach(func() {
+		originalCommand = exec.GetCommand()
+	})
+
+	AfterEach(func() {
+		exec.SetCommand(originalCommand)
+	})
+
+	It("lists staged paths matching the pathspecs", func() {
+		exec.SetCommand(func(name string, args ...string) exec.Cmd {
+			Expect(args).To(Equal([]string{"diff", "--cached", "--name-only", "HEAD^", "--", ":(top,glob)**/go.sum"}))
+			return exec.NewMockCmd([]byte("go.sum\ntools/go.sum\n"), nil)
+		})
+
+		files, err := git.StagedFiles("HEAD^", ":(top,glob)**/go.sum")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"go.sum", "tools/go.sum"}))
+	})
+})
@@ -0,0 +1,93 @@
+// Package term provides small helpers for interactive terminal output, such as
+// detecting whether a file is a terminal and rendering a progress spinner.
+package term
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsTerminal reports whether f is connected to a terminal (a character
+// device), as opposed to a pipe or regular file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// spinnerFrames are drawn in order, one per tick.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner animates a progress indicator followed by a label on a single
+// terminal line until it's stopped.
+type Spinner struct {
+	w        io.Writer
+	label    string
+	interval time.Duration
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewSpinner returns a Spinner that draws to w. Call Start to begin animating.
+func NewSpinner(w io.Writer, label string) *Spinner {
+	return &Spinner{
+		w:        w,
+		label:    label,
+		interval: 100 * time.Millisecond,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins animating the spinner in the background.
+func (s *Spinner) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			fmt.Fprintf(s.w, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], s.label)
+
+			select {
+			case <-s.stop:
+				// Clear the line so that subsequent output starts cleanly.
+				fmt.Fprint(s.w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the animation and clears the spinner's line. It blocks until the
+// line is cleared and is safe to call more than once.
+func (s *Spinner) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// Prompt writes the question to w and returns the next line read from r, with
+// surrounding whitespace removed. It returns io.EOF if r is exhausted before
+// any input is read.
+func Prompt(r *bufio.Reader, w io.Writer, question string) (string, error) {
+	fmt.Fprint(w, question)
+
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
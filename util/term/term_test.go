@@ -0,0 +1,110 @@
+package term_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/util/term"
+)
+
+func TestTerm(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Term Suite")
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+var _ = Describe("IsTerminal", func() {
+	It("returns false for regular files", func() {
+		f, err := os.Create(filepath.Join(GinkgoT().TempDir(), "out"))
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		Expect(term.IsTerminal(f)).To(BeFalse())
+	})
+
+	It("returns false for pipes", func() {
+		r, w, err := os.Pipe()
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		defer w.Close()
+
+		Expect(term.IsTerminal(w)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Spinner", func() {
+	It("draws the label until stopped, then clears the line", func() {
+		out := &syncBuffer{}
+		spinner := term.NewSpinner(out, "Generating")
+
+		spinner.Start()
+		Eventually(out.String).Should(ContainSubstring("Generating"))
+		spinner.Stop()
+
+		Expect(out.String()).To(HaveSuffix("\r\033[K"))
+	})
+
+	It("can be stopped more than once", func() {
+		spinner := term.NewSpinner(&syncBuffer{}, "Generating")
+		spinner.Start()
+		spinner.Stop()
+		Expect(spinner.Stop).NotTo(Panic())
+	})
+})
+
+var _ = Describe("Prompt", func() {
+	It("writes the question and returns the trimmed answer", func() {
+		out := &bytes.Buffer{}
+		in := bufio.NewReader(strings.NewReader("  yes  \nno\n"))
+
+		answer, err := term.Prompt(in, out, "Continue? ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(answer).To(Equal("yes"))
+		Expect(out.String()).To(Equal("Continue? "))
+
+		answer, err = term.Prompt(in, out, "Again? ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(answer).To(Equal("no"))
+	})
+
+	It("returns a final line without a trailing newline", func() {
+		in := bufio.NewReader(strings.NewReader("last"))
+
+		answer, err := term.Prompt(in, &bytes.Buffer{}, "? ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(answer).To(Equal("last"))
+	})
+
+	It("returns io.EOF when the input is exhausted", func() {
+		in := bufio.NewReader(strings.NewReader(""))
+
+		_, err := term.Prompt(in, &bytes.Buffer{}, "? ")
+		Expect(err).To(MatchError(io.EOF))
+	})
+})
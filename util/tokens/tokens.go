@@ -0,0 +1,92 @@
+// Package tokens estimates how many tokens text will use and how many a model
+// can accept, so that prompts can be kept within a model's context window
+// without depending on any provider's tokenizer.
+package tokens
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// charsPerToken is the average number of characters per token for code
+	// and English text with common BPE tokenizers.
+	charsPerToken = 4
+
+	// DefaultContextWindow is assumed for models that aren't in
+	// contextWindows.
+	DefaultContextWindow = 8_192
+
+	// MaxBudget caps the default budget so that models with very large
+	// context windows don't get very large (and expensive) prompts.
+	MaxBudget = 32_000
+)
+
+// contextWindows maps model name prefixes to their context window sizes, in
+// tokens. The longest matching prefix wins.
+var contextWindows = map[string]int{
+	"gpt-3.5":     16_385,
+	"gpt-4":       8_192,
+	"gpt-4-turbo": 128_000,
+	"gpt-4o":      128_000,
+	"gpt-4.1":     1_047_576,
+	"o1":          200_000,
+	"o3":          200_000,
+	"o4":          200_000,
+	"claude":      200_000,
+	"llama3":      8_192,
+	"llama3.1":    128_000,
+	"llama3.2":    128_000,
+	"mistral":     32_768,
+	"qwen2.5":     32_768,
+	"gemma":       8_192,
+}
+
+// Estimate returns the approximate number of tokens in text.
+func Estimate(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Truncate returns the longest prefix of text that's estimated to fit in n
+// tokens, cut at a line boundary when possible.
+func Truncate(text string, n int) string {
+	if Estimate(text) <= n {
+		return text
+	}
+	if n <= 0 {
+		return ""
+	}
+
+	// Walk forward to the byte offset of the n*charsPerToken'th rune.
+	limit, runes := 0, 0
+	for limit < len(text) && runes < n*charsPerToken {
+		_, size := utf8.DecodeRuneInString(text[limit:])
+		limit += size
+		runes++
+	}
+
+	cut := text[:limit]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut
+}
+
+// ContextWindow returns the context window size of the given model, in
+// tokens. Unknown models get DefaultContextWindow.
+func ContextWindow(model string) int {
+	best, window := "", DefaultContextWindow
+	for prefix, size := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, window = prefix, size
+		}
+	}
+	return window
+}
+
+// Budget returns the default number of tokens a diff may use in a prompt for
+// the given model: half of its context window, leaving room for the rest of
+// the prompt and the response, up to MaxBudget.
+func Budget(model string) int {
+	return min(ContextWindow(model)/2, MaxBudget)
+}
@@ -0,0 +1,60 @@
+package tokens_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivy/git-auto-commit/util/tokens"
+)
+
+func TestTokens(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tokens Suite")
+}
+
+var _ = Describe("Estimate", func() {
+	It("counts about four characters per token", func() {
+		Expect(tokens.Estimate("")).To(Equal(0))
+		Expect(tokens.Estimate("abcd")).To(Equal(1))
+		Expect(tokens.Estimate("abcde")).To(Equal(2))
+		Expect(tokens.Estimate(strings.Repeat("é", 8))).To(Equal(2))
+	})
+})
+
+var _ = Describe("Truncate", func() {
+	It("returns short text unchanged", func() {
+		Expect(tokens.Truncate("hello\n", 10)).To(Equal("hello\n"))
+	})
+
+	It("cuts long text at a line boundary", func() {
+		text := "aaaa\nbbbb\ncccc\n"
+		Expect(tokens.Truncate(text, 3)).To(Equal("aaaa\nbbbb\n"))
+	})
+
+	It("cuts mid-line when there's no earlier line break", func() {
+		Expect(tokens.Truncate(strings.Repeat("a", 20), 2)).To(Equal("aaaaaaaa"))
+	})
+})
+
+var _ = Describe("ContextWindow", func() {
+	DescribeTable("uses the longest matching prefix",
+		func(model string, expected int) {
+			Expect(tokens.ContextWindow(model)).To(Equal(expected))
+		},
+		Entry("gpt-4o-mini", "gpt-4o-mini", 128_000),
+		Entry("gpt-4", "gpt-4", 8_192),
+		Entry("claude", "claude-3-5-haiku-latest", 200_000),
+		Entry("llama3.2", "llama3.2:3b", 128_000),
+		Entry("unknown", "my-local-model", tokens.DefaultContextWindow),
+	)
+})
+
+var _ = Describe("Budget", func() {
+	It("uses half the context window, up to MaxBudget", func() {
+		Expect(tokens.Budget("gpt-4")).To(Equal(4_096))
+		Expect(tokens.Budget("gpt-4o")).To(Equal(tokens.MaxBudget))
+	})
+})